@@ -0,0 +1,125 @@
+package ics
+
+import (
+	"time"
+)
+
+// CalendarBuilder constructs a Calendar through chained calls, for example:
+//
+//	cal := NewCalendarBuilder().ProductID("-//Example//EN").Method(MethodPublish).AddEvent(event).Build()
+type CalendarBuilder struct {
+	calendar *Calendar
+}
+
+// NewCalendarBuilder returns a builder for a calendar with the same defaults as NewCalendar.
+func NewCalendarBuilder() *CalendarBuilder {
+	return &CalendarBuilder{calendar: NewCalendar()}
+}
+
+func (b *CalendarBuilder) ProductID(s string) *CalendarBuilder {
+	b.calendar.SetProductId(s)
+	return b
+}
+
+func (b *CalendarBuilder) Version(s string) *CalendarBuilder {
+	b.calendar.SetVersion(s)
+	return b
+}
+
+func (b *CalendarBuilder) Method(method Method) *CalendarBuilder {
+	b.calendar.SetMethod(method)
+	return b
+}
+
+func (b *CalendarBuilder) AddEvent(e *VEvent) *CalendarBuilder {
+	b.calendar.AddVEvent(e)
+	return b
+}
+
+func (b *CalendarBuilder) AddTodo(t *VTodo) *CalendarBuilder {
+	b.calendar.AddVTodo(t)
+	return b
+}
+
+func (b *CalendarBuilder) AddTimezone(tz *VTimezone) *CalendarBuilder {
+	b.calendar.AddTimezone(tz)
+	return b
+}
+
+// Build returns the calendar. The builder shouldn't be used afterwards as further calls would change the same
+// calendar.
+func (b *CalendarBuilder) Build() *Calendar {
+	return b.calendar
+}
+
+// EventBuilder constructs a VEvent through chained calls, for example:
+//
+//	event := NewEventBuilder().Summary("Lunch").Start(start).End(end).Location("Cafe").Build()
+type EventBuilder struct {
+	event *VEvent
+}
+
+func NewEventBuilder() *EventBuilder {
+	return &EventBuilder{event: &VEvent{}}
+}
+
+func (b *EventBuilder) UID(uid string) *EventBuilder {
+	b.event.SetUID(uid)
+	return b
+}
+
+func (b *EventBuilder) Summary(s string) *EventBuilder {
+	b.event.SetSummary(s)
+	return b
+}
+
+func (b *EventBuilder) Start(t time.Time) *EventBuilder {
+	b.event.SetStartAt(t)
+	return b
+}
+
+func (b *EventBuilder) End(t time.Time) *EventBuilder {
+	b.event.SetEndAt(t)
+	return b
+}
+
+// AllDay makes the event last the whole of the given date, as VEvent.SetAllDay does.
+func (b *EventBuilder) AllDay(date time.Time) *EventBuilder {
+	b.event.SetAllDay(date)
+	return b
+}
+
+func (b *EventBuilder) Location(s string) *EventBuilder {
+	b.event.SetLocation(s)
+	return b
+}
+
+func (b *EventBuilder) Description(s string) *EventBuilder {
+	b.event.SetDescription(s)
+	return b
+}
+
+func (b *EventBuilder) Organizer(email string) *EventBuilder {
+	b.event.SetOrganizer(email)
+	return b
+}
+
+func (b *EventBuilder) AddAttendee(email string, props ...PropertyParameter) *EventBuilder {
+	b.event.AddAttendee(email, props...)
+	return b
+}
+
+// Recurrence sets the RRULE of the event, such as "FREQ=WEEKLY;BYDAY=MO", replacing any it already has.
+func (b *EventBuilder) Recurrence(rrule string) *EventBuilder {
+	b.event.SetProperty(ComponentPropertyRrule, rrule)
+	return b
+}
+
+// Build returns the event, giving it a generated UID if none was set. The builder shouldn't be used afterwards as
+// further calls would change the same event.
+func (b *EventBuilder) Build() *VEvent {
+	if p := b.event.GetProperty(ComponentPropertyUniqueId); p == nil || p.Value == "" {
+		b.event.SetUID(newUID())
+	}
+	return b.event
+}
@@ -0,0 +1,72 @@
+package ics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarBuilder(t *testing.T) {
+	tz := &VTimezone{}
+	tz.SetProperty(ComponentProperty(PropertyTzid), "Fixed")
+	cal := NewCalendarBuilder().
+		ProductID("-//Example//EN").
+		Version("2.0").
+		Method(MethodPublish).
+		AddTimezone(tz).
+		AddEvent(NewEvent("event")).
+		AddTodo(NewTodo("todo")).
+		Build()
+
+	prodID, err := cal.GetProductId()
+	assert.NoError(t, err)
+	assert.Equal(t, "-//Example//EN", prodID)
+	method, err := cal.GetMethod()
+	assert.NoError(t, err)
+	assert.Equal(t, MethodPublish, method)
+	assert.Equal(t, map[string]int{"VTIMEZONE": 1, "VEVENT": 1, "VTODO": 1}, cal.ComponentCount())
+	found, err := cal.FindTimezone("Fixed")
+	assert.NoError(t, err)
+	assert.Same(t, tz, found)
+	assert.Len(t, cal.CalendarProperties, 3)
+}
+
+func TestEventBuilder(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	event := NewEventBuilder().
+		UID("standup").
+		Summary("Standup").
+		Start(start).
+		End(start.Add(15*time.Minute)).
+		Location("Room 1").
+		Description("Daily standup").
+		Organizer("lead@example.com").
+		AddAttendee("dev@example.com", WithRSVP(true)).
+		Recurrence("FREQ=DAILY;COUNT=5").
+		Build()
+
+	assert.Equal(t, "standup", event.Id())
+	assert.Equal(t, "Standup", event.GetProperty(ComponentPropertySummary).Value)
+	got, err := event.GetStartAt()
+	assert.NoError(t, err)
+	assert.Equal(t, start, got)
+	got, err = event.GetEndAt()
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(15*time.Minute), got)
+	assert.Equal(t, "Room 1", event.GetProperty(ComponentPropertyLocation).Value)
+	assert.Equal(t, "Daily standup", event.GetProperty(ComponentPropertyDescription).Value)
+	organizer, err := event.GetOrganizer()
+	assert.NoError(t, err)
+	assert.Equal(t, "lead@example.com", organizer.Email)
+	if assert.Len(t, event.Attendees(), 1) {
+		assert.Equal(t, "dev@example.com", event.Attendees()[0].Email())
+		assert.True(t, event.Attendees()[0].RSVP())
+	}
+	assert.Equal(t, "FREQ=DAILY;COUNT=5", event.GetProperty(ComponentPropertyRrule).Value)
+
+	allDay := NewEventBuilder().AllDay(time.Date(2024, 12, 25, 0, 0, 0, 0, time.Local)).Build()
+	assert.NotEmpty(t, allDay.Id())
+	assert.Contains(t, allDay.Serialize(), "DTSTART;VALUE=DATE:20241225\r\n")
+	assert.Contains(t, allDay.Serialize(), "DURATION:P1D\r\n")
+}
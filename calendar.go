@@ -3,9 +3,17 @@ package ics
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,33 +34,51 @@ const (
 type ComponentProperty Property
 
 const (
-	ComponentPropertyUniqueId     = ComponentProperty(PropertyUid) // TEXT
-	ComponentPropertyDtstamp      = ComponentProperty(PropertyDtstamp)
-	ComponentPropertyOrganizer    = ComponentProperty(PropertyOrganizer)
-	ComponentPropertyAttendee     = ComponentProperty(PropertyAttendee)
-	ComponentPropertyAttach       = ComponentProperty(PropertyAttach)
-	ComponentPropertyDescription  = ComponentProperty(PropertyDescription) // TEXT
-	ComponentPropertyCategories   = ComponentProperty(PropertyCategories)  // TEXT
-	ComponentPropertyClass        = ComponentProperty(PropertyClass)       // TEXT
-	ComponentPropertyColor        = ComponentProperty(PropertyColor)       // TEXT
-	ComponentPropertyCreated      = ComponentProperty(PropertyCreated)
-	ComponentPropertySummary      = ComponentProperty(PropertySummary) // TEXT
-	ComponentPropertyDtStart      = ComponentProperty(PropertyDtstart)
-	ComponentPropertyDtEnd        = ComponentProperty(PropertyDtend)
-	ComponentPropertyLocation     = ComponentProperty(PropertyLocation) // TEXT
-	ComponentPropertyStatus       = ComponentProperty(PropertyStatus)   // TEXT
-	ComponentPropertyFreebusy     = ComponentProperty(PropertyFreebusy)
-	ComponentPropertyLastModified = ComponentProperty(PropertyLastModified)
-	ComponentPropertyUrl          = ComponentProperty(PropertyUrl)
-	ComponentPropertyGeo          = ComponentProperty(PropertyGeo)
-	ComponentPropertyTransp       = ComponentProperty(PropertyTransp)
-	ComponentPropertySequence     = ComponentProperty(PropertySequence)
-	ComponentPropertyExdate       = ComponentProperty(PropertyExdate)
-	ComponentPropertyExrule       = ComponentProperty(PropertyExrule)
-	ComponentPropertyRdate        = ComponentProperty(PropertyRdate)
-	ComponentPropertyRrule        = ComponentProperty(PropertyRrule)
-	ComponentPropertyAction       = ComponentProperty(PropertyAction)
-	ComponentPropertyTrigger      = ComponentProperty(PropertyTrigger)
+	ComponentPropertyUniqueId        = ComponentProperty(PropertyUid) // TEXT
+	ComponentPropertyDtstamp         = ComponentProperty(PropertyDtstamp)
+	ComponentPropertyOrganizer       = ComponentProperty(PropertyOrganizer)
+	ComponentPropertyAttendee        = ComponentProperty(PropertyAttendee)
+	ComponentPropertyAttach          = ComponentProperty(PropertyAttach)
+	ComponentPropertyDescription     = ComponentProperty(PropertyDescription) // TEXT
+	ComponentPropertyCategories      = ComponentProperty(PropertyCategories)  // TEXT
+	ComponentPropertyClass           = ComponentProperty(PropertyClass)       // TEXT
+	ComponentPropertyColor           = ComponentProperty(PropertyColor)       // TEXT
+	ComponentPropertyCreated         = ComponentProperty(PropertyCreated)
+	ComponentPropertySummary         = ComponentProperty(PropertySummary) // TEXT
+	ComponentPropertyDtStart         = ComponentProperty(PropertyDtstart)
+	ComponentPropertyDtEnd           = ComponentProperty(PropertyDtend)
+	ComponentPropertyLocation        = ComponentProperty(PropertyLocation) // TEXT
+	ComponentPropertyStatus          = ComponentProperty(PropertyStatus)   // TEXT
+	ComponentPropertyFreebusy        = ComponentProperty(PropertyFreebusy)
+	ComponentPropertyLastModified    = ComponentProperty(PropertyLastModified)
+	ComponentPropertyUrl             = ComponentProperty(PropertyUrl)
+	ComponentPropertyGeo             = ComponentProperty(PropertyGeo)
+	ComponentPropertyTransp          = ComponentProperty(PropertyTransp)
+	ComponentPropertySequence        = ComponentProperty(PropertySequence)
+	ComponentPropertyExdate          = ComponentProperty(PropertyExdate)
+	ComponentPropertyExrule          = ComponentProperty(PropertyExrule)
+	ComponentPropertyRdate           = ComponentProperty(PropertyRdate)
+	ComponentPropertyRrule           = ComponentProperty(PropertyRrule)
+	ComponentPropertyAction          = ComponentProperty(PropertyAction)
+	ComponentPropertyTrigger         = ComponentProperty(PropertyTrigger)
+	ComponentPropertyDue             = ComponentProperty(PropertyDue)
+	ComponentPropertyCompleted       = ComponentProperty(PropertyCompleted)
+	ComponentPropertyPriority        = ComponentProperty(PropertyPriority)
+	ComponentPropertyPercentComplete = ComponentProperty(PropertyPercentComplete)
+	ComponentPropertyDuration        = ComponentProperty(PropertyDuration)
+	ComponentPropertyRepeat          = ComponentProperty(PropertyRepeat)
+	ComponentPropertyRecurrenceId    = ComponentProperty(PropertyRecurrenceId)
+	ComponentPropertyComment         = ComponentProperty(PropertyComment)   // TEXT
+	ComponentPropertyRelatedTo       = ComponentProperty(PropertyRelatedTo) // TEXT
+	ComponentPropertyContact         = ComponentProperty(PropertyContact)   // TEXT
+	ComponentPropertyImage           = ComponentProperty(PropertyImage)
+	ComponentPropertyConference      = ComponentProperty(PropertyConference)
+	ComponentPropertyResources       = ComponentProperty(PropertyResources)     // TEXT
+	ComponentPropertyRequestStatus   = ComponentProperty(PropertyRequestStatus) // TEXT
+	ComponentPropertyAcknowledged    = ComponentProperty(PropertyAcknowledged)
+
+	// ComponentPropertyStructuredLocation is from draft-ietf-calext-eventpub rather than an RFC
+	ComponentPropertyStructuredLocation = ComponentProperty(PropertyStructuredLocation)
 )
 
 type Property string
@@ -63,7 +89,7 @@ const (
 	PropertyProductId       Property = "PRODID"   // TEXT
 	PropertyVersion         Property = "VERSION"  // TEXT
 	PropertyXPublishedTTL   Property = "X-PUBLISHED-TTL"
-	PropertyRefreshInterval Property = "REFRESH-INTERVAL;VALUE=DURATION"
+	PropertyRefreshInterval Property = "REFRESH-INTERVAL"
 	PropertyAttach          Property = "ATTACH"
 	PropertyCategories      Property = "CATEGORIES"  // TEXT
 	PropertyClass           Property = "CLASS"       // TEXT
@@ -72,6 +98,8 @@ const (
 	PropertyDescription     Property = "DESCRIPTION" // TEXT
 	PropertyXWRCalDesc      Property = "X-WR-CALDESC"
 	PropertyGeo             Property = "GEO"
+	PropertyImage           Property = "IMAGE"
+	PropertyConference      Property = "CONFERENCE"
 	PropertyLocation        Property = "LOCATION" // TEXT
 	PropertyPercentComplete Property = "PERCENT-COMPLETE"
 	PropertyPriority        Property = "PRIORITY"
@@ -113,22 +141,35 @@ const (
 	PropertyXWRTimezone     Property = "X-WR-TIMEZONE"
 	PropertySequence        Property = "SEQUENCE"
 	PropertyXWRCalID        Property = "X-WR-RELCALID"
+	PropertySource          Property = "SOURCE"
+	PropertyAcknowledged    Property = "ACKNOWLEDGED"
+
+	// PropertyStructuredLocation is from draft-ietf-calext-eventpub rather than an RFC
+	PropertyStructuredLocation Property = "STRUCTURED-LOCATION"
 )
 
 type Parameter string
 
 const (
 	ParameterAltrep              Parameter = "ALTREP"
+	ParameterCharset             Parameter = "CHARSET"
 	ParameterCn                  Parameter = "CN"
 	ParameterCutype              Parameter = "CUTYPE"
 	ParameterDelegatedFrom       Parameter = "DELEGATED-FROM"
 	ParameterDelegatedTo         Parameter = "DELEGATED-TO"
+	ParameterDerived             Parameter = "DERIVED"
 	ParameterDir                 Parameter = "DIR"
+	ParameterDisplay             Parameter = "DISPLAY"
+	ParameterEmail               Parameter = "EMAIL"
 	ParameterEncoding            Parameter = "ENCODING"
 	ParameterFmttype             Parameter = "FMTTYPE"
 	ParameterFbtype              Parameter = "FBTYPE"
+	ParameterFeature             Parameter = "FEATURE"
+	ParameterLabel               Parameter = "LABEL"
 	ParameterLanguage            Parameter = "LANGUAGE"
+	ParameterLoctype             Parameter = "LOCTYPE"
 	ParameterMember              Parameter = "MEMBER"
+	ParameterOrder               Parameter = "ORDER"
 	ParameterParticipationStatus Parameter = "PARTSTAT"
 	ParameterRange               Parameter = "RANGE"
 	ParameterRelated             Parameter = "RELATED"
@@ -136,7 +177,13 @@ const (
 	ParameterRole                Parameter = "ROLE"
 	ParameterRsvp                Parameter = "RSVP"
 	ParameterSentBy              Parameter = "SENT-BY"
+	ParameterSchema              Parameter = "SCHEMA"
 	ParameterTzid                Parameter = "TZID"
+	ParameterStreet              Parameter = "STREET"
+	ParameterCity                Parameter = "CITY"
+	ParameterRegion              Parameter = "REGION"
+	ParameterPostalCode          Parameter = "POSTAL-CODE"
+	ParameterCountry             Parameter = "COUNTRY"
 	ParameterValue               Parameter = "VALUE"
 )
 
@@ -194,6 +241,10 @@ const (
 	ParticipationStatusInProcess   ParticipationStatus = "IN-PROCESS"
 )
 
+func (fbt FreeBusyTimeType) KeyValue(s ...interface{}) (string, []string) {
+	return string(ParameterFbtype), []string{string(fbt)}
+}
+
 func (ps ParticipationStatus) KeyValue(s ...interface{}) (string, []string) {
 	return string(ParameterParticipationStatus), []string{string(ps)}
 }
@@ -215,6 +266,58 @@ func (ps ObjectStatus) KeyValue(s ...interface{}) (string, []string) {
 	return string(PropertyStatus), []string{ToText(string(ps))}
 }
 
+// EventStatus is the STATUS of a VEVENT.
+type EventStatus string
+
+const (
+	EventStatusTentative EventStatus = "TENTATIVE"
+	EventStatusConfirmed EventStatus = "CONFIRMED"
+	EventStatusCancelled EventStatus = "CANCELLED"
+)
+
+func (s EventStatus) valid() bool {
+	switch s {
+	case EventStatusTentative, EventStatusConfirmed, EventStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// TodoStatus is the STATUS of a VTODO.
+type TodoStatus string
+
+const (
+	TodoStatusNeedsAction TodoStatus = "NEEDS-ACTION"
+	TodoStatusCompleted   TodoStatus = "COMPLETED"
+	TodoStatusInProcess   TodoStatus = "IN-PROCESS"
+	TodoStatusCancelled   TodoStatus = "CANCELLED"
+)
+
+func (s TodoStatus) valid() bool {
+	switch s {
+	case TodoStatusNeedsAction, TodoStatusCompleted, TodoStatusInProcess, TodoStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// JournalStatus is the STATUS of a VJOURNAL.
+type JournalStatus string
+
+const (
+	JournalStatusDraft     JournalStatus = "DRAFT"
+	JournalStatusFinal     JournalStatus = "FINAL"
+	JournalStatusCancelled JournalStatus = "CANCELLED"
+)
+
+func (s JournalStatus) valid() bool {
+	switch s {
+	case JournalStatusDraft, JournalStatusFinal, JournalStatusCancelled:
+		return true
+	}
+	return false
+}
+
 type RelationshipType string
 
 const (
@@ -223,6 +326,10 @@ const (
 	RelationshipTypeSibling RelationshipType = "SIBLING"
 )
 
+func (rt RelationshipType) KeyValue(s ...interface{}) (string, []string) {
+	return string(ParameterReltype), []string{string(rt)}
+}
+
 type ParticipationRole string
 
 const (
@@ -245,12 +352,73 @@ const (
 	ActionProcedure Action = "PROCEDURE"
 )
 
-type Classification string
+// ImageDisplay is the way an IMAGE is meant to be shown, given by the DISPLAY parameter of RFC 7986.
+type ImageDisplay string
+
+const (
+	ImageDisplayBadge     ImageDisplay = "BADGE"
+	ImageDisplayGraphic   ImageDisplay = "GRAPHIC"
+	ImageDisplayFullsize  ImageDisplay = "FULLSIZE"
+	ImageDisplayThumbnail ImageDisplay = "THUMBNAIL"
+)
+
+func (id ImageDisplay) KeyValue(s ...interface{}) (string, []string) {
+	return string(ParameterDisplay), []string{string(id)}
+}
+
+// ConferenceFeature is a feature of a CONFERENCE, given by the FEATURE parameter of RFC 7986.
+type ConferenceFeature string
+
+const (
+	ConferenceFeatureAudio     ConferenceFeature = "AUDIO"
+	ConferenceFeatureChat      ConferenceFeature = "CHAT"
+	ConferenceFeatureFeed      ConferenceFeature = "FEED"
+	ConferenceFeatureModerator ConferenceFeature = "MODERATOR"
+	ConferenceFeaturePhone     ConferenceFeature = "PHONE"
+	ConferenceFeatureScreen    ConferenceFeature = "SCREEN"
+	ConferenceFeatureVideo     ConferenceFeature = "VIDEO"
+	ConferenceFeatureService   ConferenceFeature = "SERVICE"
+)
+
+func (cf ConferenceFeature) KeyValue(s ...interface{}) (string, []string) {
+	return string(ParameterFeature), []string{string(cf)}
+}
+
+type AlarmTriggerRelationship string
+
+const (
+	AlarmTriggerRelationshipStart AlarmTriggerRelationship = "START"
+	AlarmTriggerRelationshipEnd   AlarmTriggerRelationship = "END"
+)
+
+func (atr AlarmTriggerRelationship) KeyValue(s ...interface{}) (string, []string) {
+	return string(ParameterRelated), []string{string(atr)}
+}
+
+// ObjectClass is the access classification given by the CLASS property.
+type ObjectClass string
+
+const (
+	ObjectClassPublic       ObjectClass = "PUBLIC"
+	ObjectClassPrivate      ObjectClass = "PRIVATE"
+	ObjectClassConfidential ObjectClass = "CONFIDENTIAL"
+)
+
+// Deprecated: use ObjectClass.
+type Classification = ObjectClass
+
+// Deprecated: use the ObjectClass constants.
+const (
+	ClassificationPublic       = ObjectClassPublic
+	ClassificationPrivate      = ObjectClassPrivate
+	ClassificationConfidential = ObjectClassConfidential
+)
+
+// CalScale is the calendar system of a calendar. Only the Gregorian calendar is supported.
+type CalScale string
 
 const (
-	ClassificationPublic       Classification = "PUBLIC"
-	ClassificationPrivate      Classification = "PRIVATE"
-	ClassificationConfidential Classification = "CONFIDENTIAL"
+	CalScaleGregorian CalScale = "GREGORIAN"
 )
 
 type Method string
@@ -289,29 +457,162 @@ func NewCalendarFor(service string) *Calendar {
 	return c
 }
 
-func (calendar *Calendar) Serialize() string {
-	b := bytes.NewBufferString("")
+// SerializeOption configures how Serialize and SerializeTo write a calendar.
+type SerializeOption func(*serializeConfig)
+
+type serializeConfig struct {
+	autoStamp     bool
+	maxLineLength int
+	strict        bool
+}
+
+// WithAutoStamp writes every event without a DTSTAMP with one of the current time, as RFC 5545 requires every event
+// to have a DTSTAMP. The events themselves are left unchanged.
+func WithAutoStamp() SerializeOption {
+	return func(cfg *serializeConfig) {
+		cfg.autoStamp = true
+	}
+}
+
+// WithStrictSerialization makes SerializeTo return an error, without writing anything, if the calendar has a METHOD
+// which doesn't apply to its components, as RFC 5546 limits a scheduling message to one type of component that the
+// METHOD applies to. Serialize and ToICal return nothing in that case.
+func WithStrictSerialization() SerializeOption {
+	return func(cfg *serializeConfig) {
+		cfg.strict = true
+	}
+}
+
+// WithMaxLineLength folds content lines to at most n octets instead of the 75 RFC 5545 allows, for transports with
+// other limits. n is kept between 10 and 998, the line limit of SMTP. BEGIN and END lines are never folded.
+func WithMaxLineLength(n int) SerializeOption {
+	if n < 10 {
+		n = 10
+	}
+	if n > 998 {
+		n = 998
+	}
+	return func(cfg *serializeConfig) {
+		cfg.maxLineLength = n
+	}
+}
+
+func (calendar *Calendar) Serialize(opts ...SerializeOption) string {
+	b := &strings.Builder{}
 	// We are intentionally ignoring the return value. _ used to communicate this to lint.
-	_ = calendar.SerializeTo(b)
+	_ = calendar.SerializeTo(b, opts...)
 	return b.String()
 }
 
-func (calendar *Calendar) SerializeTo(w io.Writer) error {
-	fmt.Fprint(w, "BEGIN:VCALENDAR", "\r\n")
-	for _, p := range calendar.CalendarProperties {
-		p.serialize(w)
+// ToICal returns the calendar serialized as bytes, the same as []byte(calendar.Serialize()) without copying the
+// string.
+func (calendar *Calendar) ToICal(opts ...SerializeOption) []byte {
+	b := &bytes.Buffer{}
+	// We are intentionally ignoring the return value. _ used to communicate this to lint.
+	_ = calendar.SerializeTo(b, opts...)
+	return b.Bytes()
+}
+
+// SerializeToFile writes the calendar to the file at path with 0644 permissions, replacing any existing content.
+func (calendar *Calendar) SerializeToFile(path string, opts ...SerializeOption) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fileError(path, err)
 	}
-	for _, c := range calendar.Components {
-		c.serialize(w)
+	w := bufio.NewWriter(f)
+	if err := calendar.SerializeTo(w, opts...); err != nil {
+		f.Close() // nolint:errcheck
+		return fileError(path, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close() // nolint:errcheck
+		return fileError(path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fileError(path, err)
 	}
-	fmt.Fprint(w, "END:VCALENDAR", "\r\n")
 	return nil
 }
 
+// fileError describes a failure writing a calendar to path, noting the conventional extension if path lacks it.
+func fileError(path string, err error) error {
+	if !strings.EqualFold(filepath.Ext(path), ".ics") {
+		return fmt.Errorf("writing calendar to %s (calendar files usually have a .ics extension): %w", path, err)
+	}
+	return fmt.Errorf("writing calendar to %s: %w", path, err)
+}
+
+// SerializeTo streams the calendar to w a content line at a time, returning the first error writing to w. Nothing more
+// is written after an error.
+func (calendar *Calendar) SerializeTo(w io.Writer, opts ...SerializeOption) error {
+	cfg := &serializeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.strict {
+		if err := calendar.checkMethod(componentTypes(calendar.Components)); err != nil {
+			return err
+		}
+	}
+	ew := &errorWriter{w: w, lineLength: cfg.maxLineLength}
+	if cfg.autoStamp {
+		ew.stamp = time.Now()
+	}
+	fmt.Fprint(ew, "BEGIN:VCALENDAR", "\r\n")
+	for _, p := range calendar.CalendarProperties {
+		p.serialize(ew)
+		if ew.err != nil {
+			return ew.err
+		}
+	}
+	// Timezones go first, as RFC 5545 recommends, so readers can resolve TZIDs as they go
+	for _, timezones := range []bool{true, false} {
+		for _, c := range calendar.Components {
+			if _, ok := c.(*VTimezone); ok != timezones {
+				continue
+			}
+			c.serialize(ew)
+			if ew.err != nil {
+				return ew.err
+			}
+		}
+	}
+	fmt.Fprint(ew, "END:VCALENDAR", "\r\n")
+	return ew.err
+}
+
+// errorWriter keeps the first error of the underlying writer and discards any writes after it. It also carries the
+// length properties are folded to, zero meaning the default, and the DTSTAMP written for events without one, zero
+// meaning none is added.
+type errorWriter struct {
+	w          io.Writer
+	err        error
+	lineLength int
+	stamp      time.Time
+}
+
+func (ew *errorWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
+
 func (calendar *Calendar) SetMethod(method Method, props ...PropertyParameter) {
 	calendar.setProperty(PropertyMethod, ToText(string(method)), props...)
 }
 
+// GetMethod returns the METHOD of the calendar in upper case, or ErrPropertyNotFound if it isn't a scheduling message.
+func (calendar *Calendar) GetMethod() (Method, error) {
+	p := calendar.getProperty(PropertyMethod)
+	if p == nil {
+		return "", ErrPropertyNotFound
+	}
+	return Method(strings.ToUpper(FromText(p.Value))), nil
+}
+
 func (calendar *Calendar) SetXPublishedTTL(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyXPublishedTTL, string(s), props...)
 }
@@ -320,18 +621,41 @@ func (calendar *Calendar) SetVersion(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyVersion, ToText(s), props...)
 }
 
+// GetVersion returns the VERSION of the calendar, or ErrPropertyNotFound if it has none.
+func (calendar *Calendar) GetVersion() (string, error) {
+	return calendar.getTextProperty(PropertyVersion)
+}
+
 func (calendar *Calendar) SetProductId(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyProductId, ToText(s), props...)
 }
 
+// GetProductId returns the PRODID of the calendar, or ErrPropertyNotFound if it has none.
+func (calendar *Calendar) GetProductId() (string, error) {
+	return calendar.getTextProperty(PropertyProductId)
+}
+
+// SetName sets the RFC 7986 NAME of the calendar, the short name subscribing clients show for it. The name is escaped
+// as TEXT, as SetDescription escapes the description; earlier versions wrote it as given, so callers escaping it
+// themselves should pass it unescaped now.
 func (calendar *Calendar) SetName(s string, props ...PropertyParameter) {
-	calendar.setProperty(PropertyName, string(s), props...)
+	calendar.setProperty(PropertyName, ToText(s), props...)
+}
+
+// GetName returns the NAME of the calendar, or ErrPropertyNotFound if it isn't set.
+func (calendar *Calendar) GetName() (string, error) {
+	return calendar.getTextProperty(PropertyName)
 }
 
 func (calendar *Calendar) SetColor(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyColor, string(s), props...)
 }
 
+// GetColor returns the COLOR of the calendar, or ErrPropertyNotFound if it isn't set.
+func (calendar *Calendar) GetColor() (string, error) {
+	return calendar.getTextProperty(PropertyColor)
+}
+
 func (calendar *Calendar) SetXWRCalName(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyXWRCalName, string(s), props...)
 }
@@ -352,22 +676,80 @@ func (calendar *Calendar) SetDescription(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyDescription, ToText(s), props...)
 }
 
+// GetDescription returns the RFC 7986 DESCRIPTION of the calendar, or ErrPropertyNotFound if it isn't set.
+func (calendar *Calendar) GetDescription() (string, error) {
+	return calendar.getTextProperty(PropertyDescription)
+}
+
+// SetCalendarDescription sets the RFC 7986 DESCRIPTION of the calendar. It is the same as SetDescription.
+func (calendar *Calendar) SetCalendarDescription(s string, props ...PropertyParameter) {
+	calendar.SetDescription(s, props...)
+}
+
+// GetCalendarDescription returns the RFC 7986 DESCRIPTION of the calendar, or ErrPropertyNotFound if it isn't set. It
+// is the same as GetDescription.
+func (calendar *Calendar) GetCalendarDescription() (string, error) {
+	return calendar.GetDescription()
+}
+
 func (calendar *Calendar) SetLastModified(t time.Time, props ...PropertyParameter) {
 	calendar.setProperty(PropertyLastModified, t.UTC().Format(icalTimestampFormatUtc), props...)
 }
 
-func (calendar *Calendar) SetRefreshInterval(s string, props ...PropertyParameter) {
-	calendar.setProperty(PropertyRefreshInterval, string(s), props...)
+// SetRefreshInterval sets how often subscribers should fetch the calendar again, as the RFC 7986 REFRESH-INTERVAL.
+func (calendar *Calendar) SetRefreshInterval(d time.Duration, props ...PropertyParameter) {
+	props = append(props, WithValue(string(ValueDataTypeDuration)))
+	calendar.setProperty(PropertyRefreshInterval, formatDuration(d), props...)
+}
+
+// GetRefreshInterval returns the REFRESH-INTERVAL of the calendar, or ErrPropertyNotFound if it isn't set.
+func (calendar *Calendar) GetRefreshInterval() (time.Duration, error) {
+	p := calendar.getProperty(PropertyRefreshInterval)
+	if p == nil {
+		return 0, ErrPropertyNotFound
+	}
+	return parseDuration(p.Value)
 }
 
 func (calendar *Calendar) SetCalscale(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyCalscale, string(s), props...)
 }
 
+// SetCalScale sets the CALSCALE of the calendar. Calendars without one are GREGORIAN, so it needn't be set.
+func (calendar *Calendar) SetCalScale(cs CalScale, props ...PropertyParameter) {
+	calendar.setProperty(PropertyCalscale, string(cs), props...)
+}
+
+// GetCalScale returns the CALSCALE of the calendar in upper case, which is GREGORIAN when it isn't given.
+func (calendar *Calendar) GetCalScale() CalScale {
+	p := calendar.getProperty(PropertyCalscale)
+	if p == nil {
+		return CalScaleGregorian
+	}
+	return CalScale(strings.ToUpper(FromText(p.Value)))
+}
+
 func (calendar *Calendar) SetTzid(s string, props ...PropertyParameter) {
 	calendar.setProperty(PropertyTzid, string(s), props...)
 }
 
+func (calendar *Calendar) getProperty(property Property) *CalendarProperty {
+	for i := range calendar.CalendarProperties {
+		if calendar.CalendarProperties[i].IANAToken == string(property) {
+			return &calendar.CalendarProperties[i]
+		}
+	}
+	return nil
+}
+
+// getTextProperty returns the unescaped value of a TEXT calendar property, or ErrPropertyNotFound if it isn't set.
+func (calendar *Calendar) getTextProperty(property Property) (string, error) {
+	if p := calendar.getProperty(property); p != nil {
+		return p.textValue(), nil
+	}
+	return "", ErrPropertyNotFound
+}
+
 func (calendar *Calendar) setProperty(property Property, value string, props ...PropertyParameter) {
 	for i := range calendar.CalendarProperties {
 		if calendar.CalendarProperties[i].IANAToken == string(property) {
@@ -394,7 +776,11 @@ func (calendar *Calendar) setProperty(property Property, value string, props ...
 	calendar.CalendarProperties = append(calendar.CalendarProperties, r)
 }
 
+// NewEvent returns an event with the given UID, or with a generated one if uniqueId is empty.
 func NewEvent(uniqueId string) *VEvent {
+	if uniqueId == "" {
+		uniqueId = newUID()
+	}
 	e := &VEvent{
 		ComponentBase{
 			Properties: []IANAProperty{
@@ -405,14 +791,206 @@ func NewEvent(uniqueId string) *VEvent {
 	return e
 }
 
+// NewEventFromTemplate returns a deep copy of the template, including its alarms, without the DTSTART, DTEND and
+// RECURRENCE-ID which are particular to an occurrence. The UID of the template is kept, as for an overridden instance
+// of a recurring event, unless overrideUID is set in which case a new UID is generated.
+func NewEventFromTemplate(template *VEvent, overrideUID bool) *VEvent {
+	e := cloneComponent(template).(*VEvent)
+	e.RemoveProperty(ComponentPropertyDtStart)
+	e.RemoveProperty(ComponentPropertyDtEnd)
+	e.RemoveProperty(ComponentPropertyRecurrenceId)
+	if overrideUID {
+		e.SetUID(newUID())
+	}
+	return e
+}
+
 func (calendar *Calendar) AddEvent(id string) *VEvent {
 	e := NewEvent(id)
 	calendar.Components = append(calendar.Components, e)
+	e.calendar = calendar
 	return e
 }
 
+// AddVEvent adds the event to the calendar, giving it a generated UID if it doesn't have one or it is empty.
 func (calendar *Calendar) AddVEvent(e *VEvent) {
+	if e.Id() == "" {
+		e.SetUID(newUID())
+	}
 	calendar.Components = append(calendar.Components, e)
+	e.calendar = calendar
+}
+
+// ComponentCount returns how many top level components of each type the calendar has, keyed by component name such
+// as VEVENT. Components nested in others, such as alarms, aren't counted.
+func (calendar *Calendar) ComponentCount() map[string]int {
+	r := map[string]int{}
+	for _, c := range calendar.Components {
+		r[strings.ToUpper(componentName(c))]++
+	}
+	return r
+}
+
+// RemoveEvent removes the first event with the UID, returning whether there was one.
+func (calendar *Calendar) RemoveEvent(uid string) bool {
+	for i := range calendar.Components {
+		switch event := calendar.Components[i].(type) {
+		case *VEvent:
+			if event.Id() == uid {
+				copy(calendar.Components[i:], calendar.Components[i+1:])
+				calendar.Components[len(calendar.Components)-1] = nil
+				calendar.Components = calendar.Components[:len(calendar.Components)-1]
+				event.setCalendar(nil)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RemoveAllEvents removes every event with the UID, such as a recurring event and its overridden instances, returning
+// how many were removed.
+func (calendar *Calendar) RemoveAllEvents(uid string) int {
+	r := calendar.Components[:0]
+	for _, c := range calendar.Components {
+		if event, ok := c.(*VEvent); ok && event.Id() == uid {
+			event.setCalendar(nil)
+			continue
+		}
+		r = append(r, c)
+	}
+	n := len(calendar.Components) - len(r)
+	for i := len(r); i < len(calendar.Components); i++ {
+		calendar.Components[i] = nil
+	}
+	calendar.Components = r
+	return n
+}
+
+func NewTodo(uniqueId string) *VTodo {
+	t := &VTodo{
+		ComponentBase{
+			Properties: []IANAProperty{
+				{BaseProperty{IANAToken: ToText(string(ComponentPropertyUniqueId)), Value: uniqueId}},
+			},
+		},
+	}
+	return t
+}
+
+func (calendar *Calendar) AddTodo(id string) *VTodo {
+	t := NewTodo(id)
+	calendar.Components = append(calendar.Components, t)
+	t.calendar = calendar
+	return t
+}
+
+func (calendar *Calendar) AddVTodo(t *VTodo) {
+	calendar.Components = append(calendar.Components, t)
+	t.calendar = calendar
+}
+
+// AddTimezone adds the timezone to the calendar so that TZIDs referring to it resolve, replacing any timezone with the
+// same TZID.
+func (calendar *Calendar) AddTimezone(tz *VTimezone) {
+	tz.calendar = calendar
+	for i, c := range calendar.Components {
+		if existing, ok := c.(*VTimezone); ok && existing.GetId() == tz.GetId() {
+			calendar.Components[i] = tz
+			return
+		}
+	}
+	calendar.Components = append(calendar.Components, tz)
+}
+
+// RemoveTimezone removes the timezone with the TZID, reporting whether there was one.
+func (calendar *Calendar) RemoveTimezone(tzid string) bool {
+	for i, c := range calendar.Components {
+		if tz, ok := c.(*VTimezone); ok && tz.GetId() == tzid {
+			calendar.Components = append(calendar.Components[:i], calendar.Components[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (calendar *Calendar) Todos() (r []*VTodo) {
+	r = []*VTodo{}
+	for i := range calendar.Components {
+		switch todo := calendar.Components[i].(type) {
+		case *VTodo:
+			r = append(r, todo)
+		}
+	}
+	return
+}
+
+func NewJournal(uniqueId string) *VJournal {
+	j := &VJournal{
+		ComponentBase{
+			Properties: []IANAProperty{
+				{BaseProperty{IANAToken: ToText(string(ComponentPropertyUniqueId)), Value: uniqueId}},
+			},
+		},
+	}
+	return j
+}
+
+func (calendar *Calendar) AddJournal(id string) *VJournal {
+	j := NewJournal(id)
+	calendar.Components = append(calendar.Components, j)
+	j.calendar = calendar
+	return j
+}
+
+func (calendar *Calendar) AddVJournal(j *VJournal) {
+	calendar.Components = append(calendar.Components, j)
+	j.calendar = calendar
+}
+
+func (calendar *Calendar) Journals() (r []*VJournal) {
+	r = []*VJournal{}
+	for i := range calendar.Components {
+		switch journal := calendar.Components[i].(type) {
+		case *VJournal:
+			r = append(r, journal)
+		}
+	}
+	return
+}
+
+func NewFreeBusy(uniqueId string) *VFreeBusy {
+	fb := &VFreeBusy{
+		ComponentBase{
+			Properties: []IANAProperty{
+				{BaseProperty{IANAToken: ToText(string(ComponentPropertyUniqueId)), Value: uniqueId}},
+			},
+		},
+	}
+	return fb
+}
+
+func (calendar *Calendar) AddFreeBusy(id string) *VFreeBusy {
+	fb := NewFreeBusy(id)
+	calendar.Components = append(calendar.Components, fb)
+	fb.calendar = calendar
+	return fb
+}
+
+func (calendar *Calendar) AddVFreeBusy(fb *VFreeBusy) {
+	calendar.Components = append(calendar.Components, fb)
+	fb.calendar = calendar
+}
+
+func (calendar *Calendar) FreeBusyBlocks() (r []*VFreeBusy) {
+	r = []*VFreeBusy{}
+	for i := range calendar.Components {
+		switch fb := calendar.Components[i].(type) {
+		case *VFreeBusy:
+			r = append(r, fb)
+		}
+	}
+	return
 }
 
 func (calendar *Calendar) Events() (r []*VEvent) {
@@ -426,6 +1004,361 @@ func (calendar *Calendar) Events() (r []*VEvent) {
 	return
 }
 
+// FreeBusy returns the times between from and to taken by opaque events, sorted and with overlapping or abutting times
+// merged. Recurring events count at each occurrence, and an occurrence overridden by another event with a RECURRENCE-ID
+// takes the times, status and transparency of the override. Cancelled events, and events whose times can't be read,
+// are left out.
+func (calendar *Calendar) FreeBusy(from, to time.Time) []Period {
+	length := func(event *VEvent) (time.Duration, error) {
+		start, end, err := event.timeRange()
+		return end.Sub(start), err
+	}
+	isBusy := func(event *VEvent) bool {
+		if transp, _ := event.GetTimeTransparency(); transp != TransparencyOpaque {
+			return false
+		}
+		status, err := event.GetStatus()
+		return err != nil || status != EventStatusCancelled
+	}
+	var busy []Period
+	for _, event := range calendar.Events() {
+		if event.GetProperty(ComponentPropertyRecurrenceId) != nil {
+			if master, ok := calendar.FindEventByUID(event.Id()); ok && master.GetProperty(ComponentPropertyRecurrenceId) == nil {
+				// The occurrence is found by expanding the master
+				continue
+			}
+		}
+		eventLength, err := length(event)
+		if err != nil {
+			continue
+		}
+		// Expand far enough back to find occurrences, overridden or not, that started before from but end after it
+		longest := eventLength
+		overrides, err := event.overrides()
+		if err != nil {
+			continue
+		}
+		for _, o := range overrides {
+			if l, err := length(o.event); err == nil && l > longest {
+				longest = l
+			}
+		}
+		occurrences, err := event.expand(from.Add(-longest), to)
+		if err != nil {
+			continue
+		}
+		for _, o := range occurrences {
+			source, l := event, eventLength
+			if o.override != nil {
+				source = o.override
+				if l, err = length(source); err != nil {
+					continue
+				}
+			}
+			if !isBusy(source) {
+				continue
+			}
+			p := Period{Start: o.start, End: o.start.Add(l)}
+			if p.Start.Before(from) {
+				p.Start = from
+			}
+			if p.End.After(to) {
+				p.End = to
+			}
+			if p.End.After(p.Start) {
+				busy = append(busy, p)
+			}
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool {
+		return busy[i].Start.Before(busy[j].Start)
+	})
+	var r []Period
+	for _, p := range busy {
+		if n := len(r); n > 0 && !p.Start.After(r[n-1].End) {
+			if p.End.After(r[n-1].End) {
+				r[n-1].End = p.End
+			}
+			continue
+		}
+		r = append(r, p)
+	}
+	return r
+}
+
+// EventsSorted returns the events ordered by DTSTART. All-day events, which start at midnight, come before timed
+// events starting at the same time, and events without a DTSTART that can be read come last. Events are otherwise kept
+// in calendar order.
+func (calendar *Calendar) EventsSorted() []*VEvent {
+	type sortable struct {
+		event  *VEvent
+		start  time.Time
+		ok     bool
+		allDay bool
+	}
+	events := calendar.Events()
+	l := make([]sortable, len(events))
+	for i, e := range events {
+		start, err := e.GetStartAt()
+		l[i] = sortable{event: e, start: start, ok: err == nil, allDay: e.IsAllDay()}
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i], l[j]
+		if !a.ok || !b.ok {
+			return a.ok && !b.ok
+		}
+		if !a.start.Equal(b.start) {
+			return a.start.Before(b.start)
+		}
+		return a.allDay && !b.allDay
+	})
+	r := make([]*VEvent, len(l))
+	for i := range l {
+		r[i] = l[i].event
+	}
+	return r
+}
+
+// EventsPage returns at most limit events of EventsSorted, skipping the first offset, like LIMIT and OFFSET in SQL. A
+// negative offset or limit is treated as zero.
+func (calendar *Calendar) EventsPage(offset, limit int) []*VEvent {
+	events := calendar.EventsSorted()
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	if offset > len(events) {
+		offset = len(events)
+	}
+	if limit > len(events)-offset {
+		limit = len(events) - offset
+	}
+	return events[offset : offset+limit]
+}
+
+// WalkProperties calls fn with each calendar property in order, stopping early if fn returns false. The properties of
+// the components are walked with Component.WalkProperties.
+func (calendar *Calendar) WalkProperties(fn func(Property, *IANAProperty) bool) {
+	for i := range calendar.CalendarProperties {
+		if !fn(Property(calendar.CalendarProperties[i].IANAToken), (*IANAProperty)(&calendar.CalendarProperties[i])) {
+			return
+		}
+	}
+}
+
+// Clone returns a deep copy of the calendar, its properties and components.
+func (calendar *Calendar) Clone() *Calendar {
+	r := &Calendar{}
+	if calendar.CalendarProperties != nil {
+		r.CalendarProperties = make([]CalendarProperty, 0, len(calendar.CalendarProperties))
+		for i := range calendar.CalendarProperties {
+			r.CalendarProperties = append(r.CalendarProperties, CalendarProperty{calendar.CalendarProperties[i].clone()})
+		}
+	}
+	if calendar.Components != nil {
+		r.Components = make([]Component, 0, len(calendar.Components))
+		for _, c := range calendar.Components {
+			c = cloneComponent(c)
+			c.setCalendar(r)
+			r.Components = append(r.Components, c)
+		}
+	}
+	return r
+}
+
+// Merge returns a new calendar with the properties of the calendar and the components of both calendars. Components
+// sharing a UID and RECURRENCE-ID are included once, preferring the higher SEQUENCE and then the later DTSTAMP.
+// VTIMEZONEs sharing a TZID are included once, preferring the later LAST-MODIFIED. The calendars aren't modified.
+func (calendar *Calendar) Merge(other *Calendar) *Calendar {
+	r := &Calendar{
+		Components:         []Component{},
+		CalendarProperties: make([]CalendarProperty, 0, len(calendar.CalendarProperties)),
+	}
+	for i := range calendar.CalendarProperties {
+		r.CalendarProperties = append(r.CalendarProperties, CalendarProperty{calendar.CalendarProperties[i].clone()})
+	}
+	index := map[string]int{}
+	for _, components := range [][]Component{calendar.Components, other.Components} {
+		for _, c := range components {
+			key := mergeKey(c)
+			if i, ok := index[key]; ok && key != "" {
+				if supersedes(c, r.Components[i]) {
+					r.Components[i] = cloneComponent(c)
+				}
+				continue
+			}
+			index[key] = len(r.Components)
+			r.Components = append(r.Components, cloneComponent(c))
+		}
+	}
+	for _, c := range r.Components {
+		c.setCalendar(r)
+	}
+	return r
+}
+
+// mergeKey identifies the component for Merge, components without an identity have an empty key.
+func mergeKey(c Component) string {
+	if _, ok := c.(*VTimezone); ok {
+		if p := componentProperty(c, PropertyTzid); p != nil {
+			return string(ComponentVTimezone) + ":" + p.Value
+		}
+		return ""
+	}
+	uid := componentProperty(c, PropertyUid)
+	if uid == nil {
+		return ""
+	}
+	key := componentName(c) + ":" + uid.Value
+	if p := componentProperty(c, PropertyRecurrenceId); p != nil {
+		key += ":" + p.Value
+	}
+	return key
+}
+
+// supersedes reports whether c is a newer revision of the component than previous.
+func supersedes(c Component, previous Component) bool {
+	propertyTime := func(c Component, property Property) time.Time {
+		if p := componentProperty(c, property); p != nil {
+			if t, err := parseTimeValue(p.Value, p.ICalParameters, false, nil); err == nil {
+				return t
+			}
+		}
+		return time.Time{}
+	}
+	if _, ok := c.(*VTimezone); ok {
+		return propertyTime(c, PropertyLastModified).After(propertyTime(previous, PropertyLastModified))
+	}
+	sequence := func(c Component) int {
+		if p := componentProperty(c, PropertySequence); p != nil {
+			n, _ := strconv.Atoi(p.Value)
+			return n
+		}
+		return 0
+	}
+	if s1, s2 := sequence(c), sequence(previous); s1 != s2 {
+		return s1 > s2
+	}
+	return propertyTime(c, PropertyDtstamp).After(propertyTime(previous, PropertyDtstamp))
+}
+
+// StripPrivateEvents returns a copy of the calendar for publishing, leaving out the events, to-dos and journal entries
+// which aren't PUBLIC, as an unset CLASS is. The VTIMEZONEs are only included when a remaining component refers to
+// them. The calendar isn't modified.
+func (calendar *Calendar) StripPrivateEvents() *Calendar {
+	r := &Calendar{
+		Components:         []Component{},
+		CalendarProperties: make([]CalendarProperty, 0, len(calendar.CalendarProperties)),
+	}
+	for i := range calendar.CalendarProperties {
+		r.CalendarProperties = append(r.CalendarProperties, CalendarProperty{calendar.CalendarProperties[i].clone()})
+	}
+	keep := make([]bool, len(calendar.Components))
+	tzids := map[string]bool{}
+	for i, c := range calendar.Components {
+		switch c.(type) {
+		case *VTimezone:
+			continue
+		case *VEvent, *VTodo, *VJournal:
+			// Unrecognized classes are to be treated as PRIVATE
+			if p := componentProperty(c, PropertyClass); p != nil && !strings.EqualFold(p.Value, string(ObjectClassPublic)) {
+				continue
+			}
+		}
+		keep[i] = true
+		addTzids(c, tzids)
+	}
+	for i, c := range calendar.Components {
+		if tz, ok := c.(*VTimezone); ok {
+			keep[i] = tzids[tz.GetId()]
+		}
+		if keep[i] {
+			c = cloneComponent(c)
+			c.setCalendar(r)
+			r.Components = append(r.Components, c)
+		}
+	}
+	return r
+}
+
+// addTzids adds the TZIDs the properties of the component and its subcomponents refer to.
+func addTzids(c Component, tzids map[string]bool) {
+	c.WalkProperties(func(_ Property, p *IANAProperty) bool {
+		for _, tzid := range p.ICalParameters[string(ParameterTzid)] {
+			tzids[tzid] = true
+		}
+		return true
+	})
+	for _, sub := range c.SubComponents() {
+		addTzids(sub, tzids)
+	}
+}
+
+// EventPredicate selects events in EventsWhere.
+type EventPredicate func(*VEvent) bool
+
+// EventsWhere returns the events matching all the predicates.
+func (calendar *Calendar) EventsWhere(predicates ...EventPredicate) (r []*VEvent) {
+	r = []*VEvent{}
+	for _, event := range calendar.Events() {
+		match := true
+		for _, p := range predicates {
+			if !p(event) {
+				match = false
+				break
+			}
+		}
+		if match {
+			r = append(r, event)
+		}
+	}
+	return
+}
+
+// EventAfter matches events starting after t.
+func EventAfter(t time.Time) EventPredicate {
+	return func(event *VEvent) bool {
+		start, err := event.GetStartAt()
+		return err == nil && start.After(t)
+	}
+}
+
+// EventBefore matches events starting before t.
+func EventBefore(t time.Time) EventPredicate {
+	return func(event *VEvent) bool {
+		start, err := event.GetStartAt()
+		return err == nil && start.Before(t)
+	}
+}
+
+// EventWithStatus matches events with the STATUS, compared case insensitively.
+func EventWithStatus(status ObjectStatus) EventPredicate {
+	return func(event *VEvent) bool {
+		return strings.EqualFold(event.GetPropertyValue(PropertyStatus), string(status))
+	}
+}
+
+// EventWithUID matches events with the UID.
+func EventWithUID(uid string) EventPredicate {
+	return func(event *VEvent) bool {
+		return event.Id() == uid
+	}
+}
+
+// EventHasAttendee matches events with an attendee with the email address, compared case insensitively.
+func EventHasAttendee(email string) EventPredicate {
+	return func(event *VEvent) bool {
+		for _, attendee := range event.Attendees() {
+			if strings.EqualFold(attendee.Email(), email) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func (calendar *Calendar) Timezones() (r []*VTimezone) {
 	r = []*VTimezone{}
 	for i := range calendar.Components {
@@ -437,32 +1370,278 @@ func (calendar *Calendar) Timezones() (r []*VTimezone) {
 	return
 }
 
-func (calendar *Calendar) FindTimezone(tzid string) *VTimezone {
+// FindEventByUID returns the event with the UID. For a recurring event with overridden instances the master event,
+// which has no RECURRENCE-ID, is preferred.
+func (calendar *Calendar) FindEventByUID(uid string) (*VEvent, bool) {
+	events := calendar.FindEventsByUID(uid)
+	for _, event := range events {
+		if event.GetProperty(ComponentPropertyRecurrenceId) == nil {
+			return event, true
+		}
+	}
+	if len(events) > 0 {
+		return events[0], true
+	}
+	return nil, false
+}
+
+// FindEventsByUID returns every event with the UID, which includes the overridden instances of a recurring event.
+func (calendar *Calendar) FindEventsByUID(uid string) []*VEvent {
+	return calendar.EventsWhere(EventWithUID(uid))
+}
+
+// ErrTimezoneNotFound is returned by FindTimezone when the calendar has no VTIMEZONE with the TZID.
+var ErrTimezoneNotFound = errors.New("timezone not found")
+
+func (calendar *Calendar) FindTimezone(tzid string) (*VTimezone, error) {
 	for i := range calendar.Components {
 		switch timezone := calendar.Components[i].(type) {
 		case *VTimezone:
 			if timezone.GetId() == tzid {
-				return timezone
+				return timezone, nil
 			}
 		}
 	}
 
-	return nil
+	return nil, fmt.Errorf("%w: %s", ErrTimezoneNotFound, tzid)
 }
 
-func ParseCalendar(r io.Reader) (*Calendar, error) {
-	state := "begin"
+// ParseOption configures how ParseCalendar treats input which doesn't conform to RFC 5545.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	strict          bool
+	lenientNewlines bool
+	httpClient      *http.Client
+	maxSize         int64
+	anyContentType  bool
+}
+
+// WithStrictMode makes ParseCalendar return an error on RFC 5545 violations: unknown properties on known components,
+// missing required properties such as the UID of a VEVENT, and values which don't match their value type.
+func WithStrictMode() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.strict = true
+	}
+}
+
+// WithLenientNewlines makes a lone CR end a line as well as CRLF and LF, for calendars from senders which don't use
+// CRLF. It applies to NewCalendarStream as well as to the parse functions.
+func WithLenientNewlines() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.lenientNewlines = true
+	}
+}
+
+// WithLenientMode makes ParseCalendar carry unknown properties forward and accept values as they are. This is the
+// default.
+func WithLenientMode() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.strict = false
+	}
+}
+
+// WithHTTPClient makes ParseCalendarFromURL fetch calendars with client rather than http.DefaultClient, for timeouts,
+// authentication or proxies.
+func WithHTTPClient(client *http.Client) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.httpClient = client
+	}
+}
+
+// WithMaxSize makes ParseCalendarFromURL fail on calendars larger than n bytes rather than DefaultMaxCalendarSize. A
+// limit of zero or less disables the check.
+func WithMaxSize(n int64) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxSize = n
+	}
+}
+
+// WithAnyContentType makes ParseCalendarFromURL parse the response whatever its content type.
+func WithAnyContentType() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.anyContentType = true
+	}
+}
+
+// ParseCalendarBytes parses a calendar held in memory. The data is read in place, so it must not be modified until
+// parsing returns, but the parsed calendar holds copies of its values and doesn't refer to data afterwards.
+func ParseCalendarBytes(data []byte, opts ...ParseOption) (*Calendar, error) {
+	return ParseCalendar(bytes.NewReader(data), opts...)
+}
+
+// calendarMediaTypes are the content types accepted by ParseCalendarFromURL. Feeds are often served as plain text or
+// without a specific type, so those are accepted too.
+var calendarMediaTypes = map[string]bool{
+	"text/calendar":            true,
+	"text/x-vcalendar":         true,
+	"application/ics":          true,
+	"application/x-ics":        true,
+	"text/plain":               true,
+	"application/octet-stream": true,
+}
+
+// DefaultMaxCalendarSize is the largest calendar ParseCalendarFromURL reads unless WithMaxSize is given.
+const DefaultMaxCalendarSize = 64 << 20
+
+// ParseCalendarFromURL fetches and parses the calendar at rawURL, following redirects. It fails if the response isn't
+// a success, is larger than DefaultMaxCalendarSize, or has a content type other than a calendar, plain text or
+// unspecified, such as the HTML of a login page. WithHTTPClient, WithMaxSize and WithAnyContentType change these.
+func ParseCalendarFromURL(ctx context.Context, rawURL string, opts ...ParseOption) (*Calendar, error) {
+	cfg := &parseConfig{httpClient: http.DefaultClient, maxSize: DefaultMaxCalendarSize}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "golang-ical")
+	req.Header.Set("Accept", "text/calendar, application/ics;q=0.9, */*;q=0.1")
+	resp, err := cfg.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetching %s: %s", rawURL, resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !cfg.anyContentType {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", rawURL, err)
+		}
+		if !calendarMediaTypes[mediaType] {
+			return nil, fmt.Errorf("fetching %s: unexpected content type %s", rawURL, mediaType)
+		}
+	}
+	var body io.Reader = resp.Body
+	if cfg.maxSize > 0 {
+		if resp.ContentLength > cfg.maxSize {
+			return nil, fmt.Errorf("fetching %s: calendar is larger than %d bytes", rawURL, cfg.maxSize)
+		}
+		body = &maxSizeReader{r: resp.Body, max: cfg.maxSize, url: rawURL}
+	}
+	return ParseCalendar(body, opts...)
+}
+
+// maxSizeReader fails once more than max bytes were read from r.
+type maxSizeReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+	url  string
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	if r.read > r.max {
+		return 0, fmt.Errorf("fetching %s: calendar is larger than %d bytes", r.url, r.max)
+	}
+	// Read one byte past the limit to tell a calendar of exactly max bytes from a larger one
+	if left := r.max - r.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.max {
+		return n - 1, fmt.Errorf("fetching %s: calendar is larger than %d bytes", r.url, r.max)
+	}
+	return n, err
+}
+
+// ParseCalendar reads a calendar from r. A UTF-8 byte order mark at the start of the input is skipped.
+func ParseCalendar(r io.Reader, opts ...ParseOption) (*Calendar, error) {
+	cfg := &parseConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
 	c := &Calendar{}
-	cs := NewCalendarStream(r)
+	partial, err := NewCalendarStream(r, opts...).parseCalendar(c, func(co Component) error {
+		c.Components = append(c.Components, co)
+		return nil
+	})
+	if err != nil {
+		if partial {
+			return c, err
+		}
+		return nil, err
+	}
+	if cfg.strict {
+		if err := c.checkStrict(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// CalendarItem is a top level component of a calendar read by ParseCalendarStream: a *VEvent, *VTodo, *VJournal,
+// *VFreeBusy, *VTimezone or, for other components, a *GeneralComponent.
+type CalendarItem interface {
+	Component
+}
+
+// ParseCalendarStream parses a calendar a component at a time, sending each to the item channel as soon as it's read
+// so large calendars needn't be held in memory. Only the calendar properties and timezones are kept, so TZIDs still
+// resolve. The item channel is closed once parsing ends, after which the error channel yields the error parsing
+// stopped with, if any. Parsing waits for each item to be received, so callers that stop reading early must cancel ctx
+// to end it; the error is then that of ctx. In strict mode the components are checked as they are read, while the
+// calendar properties, and the METHOD against the components, are checked once the whole calendar was read.
+func ParseCalendarStream(ctx context.Context, r io.Reader, opts ...ParseOption) (<-chan CalendarItem, <-chan error) {
+	cfg := &parseConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	items := make(chan CalendarItem)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(items)
+		c := &Calendar{}
+		var types []ComponentType
+		seen := map[ComponentType]bool{}
+		_, err := NewCalendarStream(r, opts...).parseCalendar(c, func(co Component) error {
+			if cfg.strict {
+				if err := checkComponentsStrict([]Component{co}); err != nil {
+					return err
+				}
+				if t := ComponentType(componentName(co)); !seen[t] {
+					seen[t] = true
+					types = append(types, t)
+				}
+			}
+			if tz, ok := co.(*VTimezone); ok {
+				c.Components = append(c.Components, tz)
+			}
+			select {
+			case items <- co:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err == nil && cfg.strict {
+			err = c.checkCalendarStrict(types)
+		}
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return items, errs
+}
+
+// parseCalendar reads a calendar from the stream into c, passing each top level component to add rather than adding it
+// to c. Partial is set when reading the stream failed, in which case c holds what was read before.
+func (cs *CalendarStream) parseCalendar(c *Calendar, add func(Component) error) (partial bool, err error) {
+	state := "begin"
 	cont := true
-	for ln := 0; cont; ln++ {
+	for cont {
 		l, err := cs.ReadLine()
 		if err != nil {
 			switch err {
 			case io.EOF:
 				cont = false
 			default:
-				return c, err
+				return true, cs.parseError(err)
 			}
 		}
 		if l == nil || len(*l) == 0 {
@@ -470,10 +1649,10 @@ func ParseCalendar(r io.Reader) (*Calendar, error) {
 		}
 		line, err := ParseProperty(*l)
 		if err != nil {
-			return nil, fmt.Errorf("parsing line %d: %w", ln, err)
+			return false, cs.parseError(err)
 		}
 		if line == nil {
-			return nil, fmt.Errorf("parsing calendar line %d", ln)
+			return false, cs.parseError(errors.New("parsing calendar line"))
 		}
 		switch state {
 		case "begin":
@@ -483,10 +1662,10 @@ func ParseCalendar(r io.Reader) (*Calendar, error) {
 				case "VCALENDAR":
 					state = "properties"
 				default:
-					return nil, errors.New("malformed calendar; expected a vcalendar")
+					return false, cs.parseError(errors.New("malformed calendar; expected a vcalendar"))
 				}
 			default:
-				return nil, errors.New("malformed calendar; expected begin")
+				return false, cs.parseError(errors.New("malformed calendar; expected begin"))
 			}
 		case "properties":
 			switch line.IANAToken {
@@ -495,7 +1674,7 @@ func ParseCalendar(r io.Reader) (*Calendar, error) {
 				case "VCALENDAR":
 					state = "end"
 				default:
-					return nil, errors.New("malformed calendar; expected end")
+					return false, cs.parseError(errors.New("malformed calendar; expected end"))
 				}
 			case "BEGIN":
 				state = "components"
@@ -513,47 +1692,176 @@ func ParseCalendar(r io.Reader) (*Calendar, error) {
 				case "VCALENDAR":
 					state = "end"
 				default:
-					return nil, errors.New("malformed calendar; expected end")
+					return false, cs.parseError(errors.New("malformed calendar; expected end"))
 				}
 			case "BEGIN":
 				co, err := GeneralParseComponent(cs, line)
 				if err != nil {
-					return nil, err
+					return false, cs.parseError(err)
 				}
 				if co != nil {
-					c.Components = append(c.Components, co)
+					co.setCalendar(c)
+					if err := add(co); err != nil {
+						return false, err
+					}
 				}
 			default:
-				return nil, errors.New("malformed calendar; expected begin or end")
+				return false, cs.parseError(errors.New("malformed calendar; expected begin or end"))
 			}
 		case "end":
-			return nil, errors.New("malformed calendar; unexpected end")
+			return false, cs.parseError(errors.New("malformed calendar; unexpected end"))
 		default:
-			return nil, errors.New("malformed calendar; bad state")
+			return false, cs.parseError(errors.New("malformed calendar; bad state"))
 		}
 	}
-	return c, nil
+	return false, nil
 }
 
 type CalendarStream struct {
 	r io.Reader
 	b *bufio.Reader
+	// line and offset count the lines and bytes consumed so far
+	line   int
+	offset int64
+	// contentLine and contentOffset locate the start of the content line last read
+	contentLine   int
+	contentOffset int64
+}
+
+// ParseError is returned when a calendar can't be parsed. Line is the 1-based line the offending content line starts on
+// and ByteOffset is the offset of that line from the start of the input.
+type ParseError struct {
+	Cause      error
+	Line       int
+	ByteOffset int64
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing line %d (byte offset %d): %v", e.Line, e.ByteOffset, e.Cause)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Cause
 }
 
-func NewCalendarStream(r io.Reader) *CalendarStream {
+// parseError wraps err with the position of the content line last read, unless it already has one.
+func (cs *CalendarStream) parseError(err error) error {
+	if _, ok := err.(*ParseError); ok {
+		return err
+	}
+	return &ParseError{
+		Cause:      err,
+		Line:       cs.contentLine,
+		ByteOffset: cs.contentOffset,
+	}
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// NewCalendarStream returns a stream of the content lines of r. Lines may end with CRLF or LF, or with a lone CR too
+// given WithLenientNewlines. Other options are ignored.
+func NewCalendarStream(r io.Reader, opts ...ParseOption) *CalendarStream {
+	cfg := &parseConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	b := bufio.NewReader(r)
+	if cfg.lenientNewlines {
+		b = bufio.NewReader(&crNewlineReader{r: b})
+	}
 	return &CalendarStream{
 		r: r,
-		b: bufio.NewReader(r),
+		b: b,
 	}
 }
 
+// crNewlineReader replaces each CR not followed by LF with LF. As it replaces byte for byte, positions in the input
+// are unchanged.
+type crNewlineReader struct {
+	r *bufio.Reader
+	// err is an error met looking past a CR, returned once the bytes before it are read
+	err error
+}
+
+func (cr *crNewlineReader) Read(p []byte) (int, error) {
+	if cr.err != nil {
+		return 0, cr.err
+	}
+	n := 0
+	for n < len(p) {
+		c, err := cr.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if c == '\r' {
+			next, err := cr.r.Peek(1)
+			if len(next) == 0 || next[0] != '\n' {
+				c = '\n'
+			}
+			cr.err = err
+		}
+		p[n] = c
+		n++
+		if cr.r.Buffered() == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+// isQuotedPrintable reports whether the content line has an ENCODING=QUOTED-PRINTABLE parameter.
+func isQuotedPrintable(line []byte) bool {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			return bytes.Contains(bytes.ToUpper(line[:i]), []byte(";ENCODING=QUOTED-PRINTABLE"))
+		}
+	}
+	return false
+}
+
+// ReadAll reads the remaining content lines of the stream, unfolded. Empty lines are skipped and reaching the end of
+// the stream isn't an error.
+func (cs *CalendarStream) ReadAll() ([]ContentLine, error) {
+	var r []ContentLine
+	for {
+		l, err := cs.ReadLine()
+		if l != nil && len(*l) > 0 {
+			r = append(r, *l)
+		}
+		switch err {
+		case nil:
+		case io.EOF:
+			return r, nil
+		default:
+			return r, err
+		}
+	}
+}
+
+// ReadLine reads the next content line, unfolded. A UTF-8 byte order mark at the start of the stream, as some Windows
+// software writes, is skipped. The soft line breaks of values with ENCODING=QUOTED-PRINTABLE, an "=" ending a line
+// not followed by a folded one, are joined as well.
 func (cs *CalendarStream) ReadLine() (*ContentLine, error) {
+	if cs.offset == 0 {
+		if p, _ := cs.b.Peek(len(utf8BOM)); bytes.Equal(p, utf8BOM) {
+			cs.b.Discard(len(utf8BOM)) // nolint:errcheck
+			cs.offset += int64(len(utf8BOM))
+		}
+	}
 	r := []byte{}
 	c := true
 	var err error
 	for c {
+		if len(r) == 0 {
+			cs.contentLine, cs.contentOffset = cs.line+1, cs.offset
+		}
 		var b []byte
 		b, err = cs.b.ReadBytes('\n')
+		cs.offset += int64(len(b))
 		if len(b) == 0 {
 			if err == nil {
 				continue
@@ -561,6 +1869,7 @@ func (cs *CalendarStream) ReadLine() (*ContentLine, error) {
 				c = false
 			}
 		} else if b[len(b)-1] == '\n' {
+			cs.line++
 			o := 1
 			if len(b) > 1 && b[len(b)-2] == '\r' {
 				o = 2
@@ -574,6 +1883,10 @@ func (cs *CalendarStream) ReadLine() (*ContentLine, error) {
 				c = false
 			} else if p[0] == ' ' || p[0] == '\t' {
 				cs.b.Discard(1) // nolint:errcheck
+				cs.offset++
+			} else if len(r) > 0 && r[len(r)-1] == '=' && isQuotedPrintable(r) {
+				// A soft line break of a QUOTED-PRINTABLE value continues it on the next line
+				r = r[:len(r)-1]
 			} else {
 				c = false
 			}
@@ -588,7 +1901,7 @@ func (cs *CalendarStream) ReadLine() (*ContentLine, error) {
 		case io.EOF:
 			c = false
 		default:
-			return nil, err
+			return nil, cs.parseError(err)
 		}
 	}
 	if len(r) == 0 && err != nil {
@@ -1,9 +1,14 @@
 package ics
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -123,6 +128,9 @@ CLASS:PUBLIC
 		ContentLine("CATEGORIES:MEETING"),
 		ContentLine("CLASS:PUBLIC"),
 	}
+	lines, err := NewCalendarStream(strings.NewReader(i)).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, lines)
 	c := NewCalendarStream(strings.NewReader(i))
 	cont := true
 	for i := 0; cont; i++ {
@@ -311,8 +319,10 @@ X-CUSTOM-FIELD:test
 PRODID:-//arran4//Golang ICS Library
 DESCRIPTION:test
 BEGIN:VEVENT
+UID:8444ad6a293ca9d43bbb6d5eee8601f0@golang-ical
 DESCRIPTION:blablablablablablablablablablablablablablablabltesttesttest
 CLASS:PUBLIC
+SEQUENCE:0
 END:VEVENT
 END:VCALENDAR
 `,
@@ -360,3 +370,991 @@ func TestIssue52(t *testing.T) {
 		t.Fatalf("cannot read test directory: %v", err)
 	}
 }
+
+func TestParseErrorPosition(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nSUMMARY:a folded\r\n  summary\r\nDTSTART;TZID:20210101\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	_, err := ParseCalendar(strings.NewReader(input))
+	var parseErr *ParseError
+	if assert.True(t, errors.As(err, &parseErr), "%v", err) {
+		assert.Equal(t, 6, parseErr.Line)
+		assert.Equal(t, int64(strings.Index(input, "DTSTART")), parseErr.ByteOffset)
+		assert.NotNil(t, parseErr.Cause)
+	}
+
+	_, err = ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\nVERSION:2.0\nEND:VEVENT\n"))
+	if assert.True(t, errors.As(err, &parseErr), "%v", err) {
+		assert.Equal(t, 3, parseErr.Line)
+		assert.Equal(t, int64(28), parseErr.ByteOffset)
+	}
+}
+
+func TestParseCalendarStrictMode(t *testing.T) {
+	wrap := func(s string) string {
+		return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" + s + "END:VCALENDAR\r\n"
+	}
+	testCases := []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{
+			name:  "valid event",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nDTSTART;VALUE=DATE:20210101\r\nPRIORITY:1\r\nX-CUSTOM:yes\r\nEND:VEVENT\r\n"),
+			valid: true,
+		},
+		{
+			name:  "missing uid",
+			input: wrap("BEGIN:VEVENT\r\nDTSTAMP:20210101T000000Z\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "missing version",
+			input: "BEGIN:VCALENDAR\r\nPRODID:-//test//EN\r\nEND:VCALENDAR\r\n",
+		},
+		{
+			name:  "unknown property",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nFOO:bar\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "invalid integer",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nPRIORITY:high\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "invalid date-time",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "alarm without trigger",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nBEGIN:VALARM\r\nACTION:DISPLAY\r\nEND:VALARM\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "method request",
+			input: wrap("METHOD:REQUEST\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nEND:VEVENT\r\n"),
+			valid: true,
+		},
+		{
+			name:  "method not for journals",
+			input: wrap("METHOD:REQUEST\r\nBEGIN:VJOURNAL\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nEND:VJOURNAL\r\n"),
+		},
+		{
+			name: "method with mixed components",
+			input: wrap("METHOD:PUBLISH\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nEND:VEVENT\r\n" +
+				"BEGIN:VTODO\r\nUID:2\r\nDTSTAMP:20210101T000000Z\r\nEND:VTODO\r\n"),
+		},
+		{
+			name:  "gregorian calendar",
+			input: wrap("CALSCALE:GREGORIAN\r\n"),
+			valid: true,
+		},
+		{
+			name:  "other calendar",
+			input: wrap("CALSCALE:HEBREW\r\n"),
+		},
+		{
+			name:  "method without components",
+			input: wrap("METHOD:PUBLISH\r\n"),
+		},
+		{
+			name:  "color name",
+			input: wrap("COLOR:CornflowerBlue\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nCOLOR:#ff8000\r\nEND:VEVENT\r\n"),
+			valid: true,
+		},
+		{
+			name:  "unknown color",
+			input: wrap("COLOR:blurple\r\n"),
+		},
+		{
+			name:  "invalid hex color",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nCOLOR:#ff80\r\nEND:VEVENT\r\n"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseCalendar(strings.NewReader(tc.input), WithLenientMode())
+			assert.NoError(t, err)
+			_, err = ParseCalendar(strings.NewReader(tc.input), WithStrictMode())
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestEventsWhere(t *testing.T) {
+	cal := NewCalendar()
+	early := cal.AddEvent("early")
+	early.SetStartAt(time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC))
+	early.SetStatus(ObjectStatusConfirmed)
+	early.AddAttendee("Alice@example.com")
+	late := cal.AddEvent("late")
+	late.SetStartAt(time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC))
+	late.SetStatus(ObjectStatusCancelled)
+	late.AddAttendee("bob@example.com")
+	cal.AddEvent("no-start")
+
+	mid := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"early", "late", "no-start"}, eventIDs(cal.EventsWhere()))
+	assert.Equal(t, []string{"late"}, eventIDs(cal.EventsWhere(EventAfter(mid))))
+	assert.Equal(t, []string{"early"}, eventIDs(cal.EventsWhere(EventBefore(mid))))
+	assert.Equal(t, []string{"late"}, eventIDs(cal.EventsWhere(EventWithStatus(ObjectStatusCancelled))))
+	assert.Equal(t, []string{"no-start"}, eventIDs(cal.EventsWhere(EventWithUID("no-start"))))
+	assert.Equal(t, []string{"early"}, eventIDs(cal.EventsWhere(EventHasAttendee("alice@example.com"))))
+	assert.Equal(t, []string{}, eventIDs(cal.EventsWhere(EventBefore(mid), EventWithStatus(ObjectStatusCancelled))))
+}
+
+func TestMerge(t *testing.T) {
+	stamp := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewCalendarFor("a")
+	a1 := a.AddEvent("1")
+	a1.SetSummary("a1")
+	a1.SetSequence(1)
+	a2 := a.AddEvent("2")
+	a2.SetSummary("a2")
+	a2.SetDtStampTime(stamp)
+	a3 := a.AddEvent("3")
+	a3.SetSummary("a3")
+	a.AddVEvent(&VEvent{ComponentBase{Properties: []IANAProperty{{BaseProperty{IANAToken: string(PropertyLastModified), Value: "20210101T000000Z"}}}}})
+	tzA := &VTimezone{}
+	tzA.SetProperty(ComponentProperty(PropertyTzid), "Europe/Test")
+	tzA.SetProperty(ComponentPropertyLastModified, "20200101T000000Z")
+	a.Components = append(a.Components, tzA)
+
+	b := NewCalendarFor("b")
+	b1 := b.AddEvent("1")
+	b1.SetSummary("b1")
+	b2 := b.AddEvent("2")
+	b2.SetSummary("b2")
+	b2.SetDtStampTime(stamp.Add(time.Hour))
+	b3 := b.AddEvent("3")
+	b3.SetSummary("b3 override")
+	b3.SetProperty(ComponentProperty(PropertyRecurrenceId), "20210102T000000Z")
+	b4 := b.AddEvent("4")
+	b4.SetSummary("b4")
+	tzB := &VTimezone{}
+	tzB.SetProperty(ComponentProperty(PropertyTzid), "Europe/Test")
+	tzB.SetProperty(ComponentPropertyLastModified, "20210101T000000Z")
+	b.Components = append(b.Components, tzB)
+
+	merged := a.Merge(b)
+	var summaries []string
+	for _, e := range merged.Events() {
+		summaries = append(summaries, e.GetPropertyValue(PropertySummary))
+	}
+	assert.Equal(t, []string{"a1", "b2", "a3", "", "b3 override", "b4"}, summaries)
+	if assert.Len(t, merged.Timezones(), 1) {
+		assert.Equal(t, "20210101T000000Z", merged.Timezones()[0].GetPropertyValue(PropertyLastModified))
+	}
+	assert.Equal(t, a.CalendarProperties, merged.CalendarProperties)
+
+	merged.Events()[0].SetSummary("changed")
+	assert.Equal(t, "a1", a1.GetPropertyValue(PropertySummary))
+}
+
+func TestStripPrivateEvents(t *testing.T) {
+	cal := NewCalendar()
+	for _, tzid := range []string{"Europe/Public", "Europe/Private", "Europe/Unused"} {
+		tz := &VTimezone{}
+		tz.SetProperty(ComponentProperty(PropertyTzid), tzid)
+		cal.AddTimezone(tz)
+	}
+	public := cal.AddEvent("public")
+	public.SetClass(ObjectClassPublic)
+	public.SetProperty(ComponentPropertyDtStart, "20240101T090000", &KeyValues{Key: string(ParameterTzid), Value: []string{"Europe/Public"}})
+	unclassified := cal.AddEvent("unclassified")
+	alarm := unclassified.AddAlarm()
+	alarm.SetProperty(ComponentPropertyTrigger, "20240101T083000", &KeyValues{Key: string(ParameterTzid), Value: []string{"Europe/Public"}})
+	private := cal.AddEvent("private")
+	private.SetClass(ObjectClassPrivate)
+	private.SetProperty(ComponentPropertyDtStart, "20240101T090000", &KeyValues{Key: string(ParameterTzid), Value: []string{"Europe/Private"}})
+	cal.AddEvent("confidential").SetClass(ObjectClassConfidential)
+	cal.AddEvent("custom").SetProperty(ComponentPropertyClass, "X-FRIENDS-ONLY")
+	cal.AddTodo("private-todo").SetClass(ObjectClassPrivate)
+	cal.AddTodo("todo")
+
+	stripped := cal.StripPrivateEvents()
+	var uids []string
+	for _, e := range stripped.Events() {
+		uids = append(uids, e.Id())
+	}
+	assert.Equal(t, []string{"public", "unclassified"}, uids)
+	if assert.Len(t, stripped.Todos(), 1) {
+		assert.Equal(t, "todo", stripped.Todos()[0].Id())
+	}
+	if assert.Len(t, stripped.Timezones(), 1) {
+		assert.Equal(t, "Europe/Public", stripped.Timezones()[0].GetId())
+	}
+	assert.Equal(t, cal.CalendarProperties, stripped.CalendarProperties)
+
+	assert.Len(t, cal.Events(), 5)
+	assert.Len(t, cal.Timezones(), 3)
+	stripped.Events()[0].SetSummary("changed")
+	assert.Nil(t, public.GetProperty(ComponentPropertySummary))
+}
+
+func TestClone(t *testing.T) {
+	input, err := ioutil.ReadFile("./testdata/rfc5545sec4/input3.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := ParseCalendar(strings.NewReader(string(input)))
+	if !assert.NoError(t, err) {
+		return
+	}
+	serialized := original.Serialize()
+	clone := original.Clone()
+	assert.Equal(t, len(serialized), len(clone.Serialize()))
+
+	clone.SetMethod(MethodCancel)
+	for _, c := range clone.Components {
+		c.UnknownPropertiesIANAProperties()[0].Value = "changed"
+		c.UnknownPropertiesIANAProperties()[0].ICalParameters["X-CHANGED"] = []string{"1"}
+		for _, sub := range c.SubComponents() {
+			sub.UnknownPropertiesIANAProperties()[0].Value = "changed"
+		}
+	}
+	clone.Components = append(clone.Components, NewEvent("added"))
+	assert.Equal(t, serialized, original.Serialize())
+}
+
+type limitedWriter struct {
+	n   int
+	buf strings.Builder
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.n {
+		return 0, io.ErrShortWrite
+	}
+	return w.buf.Write(p)
+}
+
+func TestSerializeTo(t *testing.T) {
+	cal := NewCalendar()
+	for i := 0; i < 10; i++ {
+		cal.AddEvent(fmt.Sprintf("event-%d", i)).SetSummary("summary")
+	}
+	expected := cal.Serialize()
+
+	w := &limitedWriter{n: len(expected)}
+	assert.NoError(t, cal.SerializeTo(w))
+	assert.Equal(t, expected, w.buf.String())
+
+	w = &limitedWriter{n: len(expected) / 2}
+	assert.Equal(t, io.ErrShortWrite, cal.SerializeTo(w))
+	assert.True(t, strings.HasPrefix(expected, w.buf.String()))
+}
+
+func TestToICal(t *testing.T) {
+	cal := NewCalendar()
+	cal.AddEvent("event").SetSummary("summary")
+	assert.Equal(t, []byte(cal.Serialize()), cal.ToICal())
+	assert.Equal(t, []byte(cal.Serialize(WithMaxLineLength(20))), cal.ToICal(WithMaxLineLength(20)))
+}
+
+func TestParseCalendarBytes(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//Golang ICS Library\r\nBEGIN:VEVENT\r\nUID:123\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendarBytes([]byte(input))
+	if assert.NoError(t, err) {
+		assert.Equal(t, strings.Replace(input, "UID:123\r\n", "UID:123\r\nSEQUENCE:0\r\n", 1), cal.Serialize())
+	}
+	_, err = ParseCalendarBytes([]byte(input), WithStrictMode())
+	assert.Error(t, err)
+}
+
+func TestWalkProperties(t *testing.T) {
+	cal := NewCalendarFor("test")
+	var names []Property
+	cal.WalkProperties(func(name Property, p *IANAProperty) bool {
+		names = append(names, name)
+		return true
+	})
+	assert.Equal(t, []Property{PropertyVersion, PropertyProductId}, names)
+
+	event := cal.AddEvent("123")
+	event.SetSummary("Planning")
+	event.SetLocation("Room 1")
+	names = nil
+	cal.Components[0].WalkProperties(func(name Property, p *IANAProperty) bool {
+		names = append(names, name)
+		if name == PropertySummary {
+			p.Value = "Retro"
+			return false
+		}
+		return true
+	})
+	assert.Equal(t, []Property{PropertyUid, PropertySummary}, names)
+	assert.Equal(t, "Retro", event.GetProperty(ComponentPropertySummary).Value)
+}
+
+func TestFindEventByUID(t *testing.T) {
+	cal := NewCalendar()
+	override := cal.AddEvent("series")
+	override.SetProperty(ComponentPropertyRecurrenceId, "20240102T090000Z")
+	master := cal.AddEvent("series")
+	cal.AddEvent("other")
+
+	event, ok := cal.FindEventByUID("series")
+	assert.True(t, ok)
+	assert.Same(t, master, event)
+	assert.Equal(t, []*VEvent{override, master}, cal.FindEventsByUID("series"))
+	_, ok = cal.FindEventByUID("missing")
+	assert.False(t, ok)
+	assert.Empty(t, cal.FindEventsByUID("missing"))
+}
+
+func TestSerializeWithAutoStamp(t *testing.T) {
+	cal := NewCalendar()
+	stamped := cal.AddEvent("stamped")
+	stamped.SetDtStampTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cal.AddEvent("unstamped")
+	assert.Equal(t, 1, strings.Count(cal.Serialize(), "DTSTAMP:"))
+
+	before := time.Now().Truncate(time.Second)
+	out := cal.Serialize(WithAutoStamp())
+	assert.Equal(t, 2, strings.Count(out, "DTSTAMP:"))
+	assert.Contains(t, out, "DTSTAMP:20240101T000000Z\r\n")
+	stamps := regexp.MustCompile(`DTSTAMP:(\d{8}T\d{6}Z)\r\n`).FindAllStringSubmatch(out, -1)
+	if assert.Len(t, stamps, 2) {
+		stamp, err := time.Parse(icalTimestampFormatUtc, stamps[1][1])
+		assert.NoError(t, err)
+		assert.False(t, stamp.Before(before))
+	}
+	assert.Nil(t, cal.Events()[1].GetProperty(ComponentPropertyDtstamp))
+	assert.Equal(t, 1, strings.Count(cal.Serialize(), "DTSTAMP:"))
+}
+
+func TestRemoveEvent(t *testing.T) {
+	cal := NewCalendar()
+	first := cal.AddEvent("series")
+	cal.AddTodo("series")
+	cal.AddEvent("other")
+	override := cal.AddEvent("series")
+	override.SetProperty(ComponentPropertyRecurrenceId, "20240102T090000Z")
+
+	components := cal.Components
+	assert.True(t, cal.RemoveEvent("series"))
+	assert.Equal(t, []string{"other", "series"}, eventIDs(cal.Events()))
+	assert.Len(t, cal.Todos(), 1)
+	assert.Nil(t, components[len(components)-1])
+	assert.Nil(t, first.calendar)
+	assert.False(t, cal.RemoveEvent("missing"))
+
+	added := cal.AddEvent("series")
+	assert.Equal(t, 2, cal.RemoveAllEvents("series"))
+	assert.Equal(t, []string{"other"}, eventIDs(cal.Events()))
+	assert.Len(t, cal.Todos(), 1)
+	assert.Nil(t, override.calendar)
+	assert.Nil(t, added.calendar)
+	assert.Equal(t, 0, cal.RemoveAllEvents("series"))
+}
+
+func eventIDs(events []*VEvent) (r []string) {
+	r = []string{}
+	for _, e := range events {
+		r = append(r, e.Id())
+	}
+	return
+}
+
+func TestMethod(t *testing.T) {
+	cal := NewCalendar()
+	_, err := cal.GetMethod()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	cal.SetMethod(MethodCancel)
+	method, err := cal.GetMethod()
+	assert.NoError(t, err)
+	assert.Equal(t, MethodCancel, method)
+}
+
+func TestSerializeStrictMethod(t *testing.T) {
+	cal := NewCalendar()
+	cal.SetMethod(MethodRequest)
+	assert.Error(t, cal.SerializeTo(ioutil.Discard, WithStrictSerialization()))
+	assert.Equal(t, "", cal.Serialize(WithStrictSerialization()))
+	cal.AddEvent("event")
+	assert.NoError(t, cal.SerializeTo(ioutil.Discard, WithStrictSerialization()))
+	cal.AddJournal("journal")
+	assert.Error(t, cal.SerializeTo(ioutil.Discard, WithStrictSerialization()))
+	assert.NoError(t, cal.SerializeTo(ioutil.Discard))
+}
+
+func TestNewEventFromTemplate(t *testing.T) {
+	template := NewEvent("series")
+	template.SetSummary("Standup")
+	template.SetStartAt(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	template.SetEndAt(time.Date(2024, 1, 1, 9, 15, 0, 0, time.UTC))
+	template.SetRecurrenceID(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), false)
+	template.AddAttendee("alice@example.com")
+	template.AddAlarm().SetAction(ActionDisplay)
+
+	e := NewEventFromTemplate(template, false)
+	assert.Equal(t, "series", e.Id())
+	assert.Equal(t, "Standup", e.GetProperty(ComponentPropertySummary).Value)
+	assert.Nil(t, e.GetProperty(ComponentPropertyDtStart))
+	assert.Nil(t, e.GetProperty(ComponentPropertyDtEnd))
+	assert.Nil(t, e.GetProperty(ComponentPropertyRecurrenceId))
+	assert.Len(t, e.Attendees(), 1)
+	assert.Len(t, e.Alarms(), 1)
+
+	e.SetSummary("Retro")
+	e.Attendees()[0].ICalParameters["CN"] = []string{"Alice"}
+	e.Alarms()[0].SetAction(ActionAudio)
+	assert.Equal(t, "Standup", template.GetProperty(ComponentPropertySummary).Value)
+	assert.Empty(t, template.Attendees()[0].ICalParameters)
+	assert.Equal(t, string(ActionDisplay), template.Alarms()[0].GetProperty(ComponentPropertyAction).Value)
+
+	assert.NotEqual(t, "series", NewEventFromTemplate(template, true).Id())
+}
+
+func TestComponentCount(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nBEGIN:VALARM\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:2\r\nEND:VEVENT\r\nBEGIN:VTODO\r\nUID:3\r\nEND:VTODO\r\n" +
+		"BEGIN:X-CUSTOM\r\nEND:X-CUSTOM\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]int{"VEVENT": 2, "VTODO": 1, "X-CUSTOM": 1}, cal.ComponentCount())
+	}
+	assert.Equal(t, map[string]int{}, NewCalendar().ComponentCount())
+}
+
+func TestCalscale(t *testing.T) {
+	cal := NewCalendar()
+	assert.Equal(t, CalScaleGregorian, cal.GetCalScale())
+	assert.NotContains(t, cal.Serialize(), "CALSCALE")
+	cal.SetCalScale(CalScaleGregorian)
+	assert.Contains(t, cal.Serialize(), "CALSCALE:GREGORIAN\r\n")
+	cal.SetCalscale("chinese")
+	assert.Equal(t, CalScale("CHINESE"), cal.GetCalScale())
+}
+
+func TestVersionAndProductId(t *testing.T) {
+	cal := NewCalendarFor("test")
+	version, err := cal.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0", version)
+	prodID, err := cal.GetProductId()
+	assert.NoError(t, err)
+	assert.Equal(t, "-//test//Golang ICS Library", prodID)
+	cal.SetProductId("-//Example Corp.//CalDAV Client//EN")
+	cal.SetVersion("2.0")
+	prodID, err = cal.GetProductId()
+	assert.NoError(t, err)
+	assert.Equal(t, "-//Example Corp.//CalDAV Client//EN", prodID)
+	_, err = (&Calendar{}).GetVersion()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+}
+
+func TestParseCalendarStream(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VTIMEZONE
+TZID:Fixed
+BEGIN:STANDARD
+DTSTART:16010101T000000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0200
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:one
+DTSTART;TZID=Fixed:20240101T120000
+END:VEVENT
+BEGIN:VTODO
+UID:two
+END:VTODO
+END:VCALENDAR
+`
+	items, errs := ParseCalendarStream(context.Background(), strings.NewReader(input))
+	var got []CalendarItem
+	for item := range items {
+		got = append(got, item)
+	}
+	assert.NoError(t, <-errs)
+	if assert.Len(t, got, 3) {
+		assert.IsType(t, &VTimezone{}, got[0])
+		assert.IsType(t, &VTodo{}, got[2])
+		event, ok := got[1].(*VEvent)
+		if assert.True(t, ok) {
+			start, err := event.GetStartAt()
+			assert.NoError(t, err)
+			assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), start.UTC())
+		}
+	}
+
+	items, errs = ParseCalendarStream(context.Background(), strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT\r\n"))
+	for range items {
+		t.Fatal("unexpected item")
+	}
+	assert.Error(t, <-errs)
+}
+
+func TestParseCalendarStreamCancel(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" +
+		strings.Repeat("BEGIN:VEVENT\r\nUID:x\r\nDTSTAMP:20240101T000000Z\r\nEND:VEVENT\r\n", 10) + "END:VCALENDAR\r\n"
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errs := ParseCalendarStream(ctx, strings.NewReader(input))
+	<-items
+	cancel()
+	for range items {
+	}
+	assert.True(t, errors.Is(<-errs, context.Canceled))
+}
+
+func TestParseCalendarStreamStrict(t *testing.T) {
+	for _, extra := range []string{"METHOD:REQUEST\r\n", "CALSCALE:JULIAN\r\n"} {
+		input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" + extra +
+			"BEGIN:VJOURNAL\r\nUID:x\r\nDTSTAMP:20240101T000000Z\r\nEND:VJOURNAL\r\nEND:VCALENDAR\r\n"
+		_, err := ParseCalendar(strings.NewReader(input), WithStrictMode())
+		if !assert.Error(t, err, extra) {
+			continue
+		}
+		items, errs := ParseCalendarStream(context.Background(), strings.NewReader(input), WithStrictMode())
+		for range items {
+		}
+		assert.Equal(t, err, <-errs, extra)
+	}
+}
+
+func TestLenientNewlines(t *testing.T) {
+	input := "BEGIN:VCALENDAR\rVERSION:2.0\nBEGIN:VEVENT\r\nUID:one\rSUMMARY:Long\r  summary\rEND:VEVENT\rEND:VCALENDAR\r"
+
+	lines, err := NewCalendarStream(strings.NewReader(input), WithLenientNewlines()).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []ContentLine{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:one",
+		"SUMMARY:Long summary",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, lines)
+
+	cal, err := ParseCalendar(strings.NewReader(input), WithLenientNewlines())
+	if assert.NoError(t, err) && assert.Len(t, cal.Events(), 1) {
+		assert.Equal(t, "Long summary", cal.Events()[0].GetProperty(ComponentPropertySummary).Value)
+	}
+
+	_, err = ParseCalendar(strings.NewReader(input))
+	assert.Error(t, err)
+}
+
+func TestParseCalendarBOM(t *testing.T) {
+	input := "\xEF\xBB\xBFBEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:one\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if assert.NoError(t, err) {
+		assert.Len(t, cal.Events(), 1)
+		version, err := cal.GetVersion()
+		assert.NoError(t, err)
+		assert.Equal(t, "2.0", version)
+	}
+
+	_, err = ParseCalendar(strings.NewReader("\xEF\xBB\xBFBEGIN:VEVENT\r\n"))
+	var perr *ParseError
+	if assert.True(t, errors.As(err, &perr)) {
+		assert.Equal(t, 1, perr.Line)
+		assert.Equal(t, int64(3), perr.ByteOffset)
+	}
+}
+
+func TestParseCalendarFromURL(t *testing.T) {
+	const feed = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:one\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/feed.ics", http.StatusFound)
+		case "/feed.ics":
+			assert.Equal(t, "golang-ical", r.UserAgent())
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			fmt.Fprint(w, feed)
+		case "/login":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html></html>")
+		case "/legacy.ics":
+			w.Header().Set("Content-Type", "text/x-vcalendar")
+			fmt.Fprint(w, feed)
+		case "/chunked.ics":
+			w.Header().Set("Content-Type", "text/calendar")
+			fmt.Fprint(w, feed)
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, feed)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cal, err := ParseCalendarFromURL(context.Background(), server.URL+"/old")
+	if assert.NoError(t, err) {
+		assert.Len(t, cal.Events(), 1)
+	}
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/login")
+	assert.Error(t, err)
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/legacy.ics")
+	assert.NoError(t, err)
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/login", WithAnyContentType())
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "content type")
+
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/feed.ics", WithMaxSize(int64(len(feed))))
+	assert.NoError(t, err)
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/feed.ics", WithMaxSize(int64(len(feed)-1)))
+	assert.Error(t, err)
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/chunked.ics", WithMaxSize(int64(len(feed))))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "larger than")
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	_, err = ParseCalendarFromURL(context.Background(), server.URL+"/old", WithHTTPClient(client))
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ParseCalendarFromURL(ctx, server.URL+"/feed.ics")
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+}
+
+func TestSerializeToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cal := NewCalendar()
+	cal.AddEvent("one")
+	path := filepath.Join(dir, "calendar.ics")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(strings.Repeat("stale content\n", 100)), 0600))
+	assert.NoError(t, cal.SerializeToFile(path))
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, cal.Serialize(), string(b))
+
+	err = cal.SerializeToFile(filepath.Join(dir, "missing", "calendar"))
+	if assert.Error(t, err) {
+		assert.True(t, os.IsNotExist(errors.Unwrap(err)), "%v", err)
+		assert.Contains(t, err.Error(), ".ics")
+	}
+}
+
+func TestEventsSorted(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VEVENT
+UID:no-start
+END:VEVENT
+BEGIN:VEVENT
+UID:afternoon
+DTSTART:20240102T150000
+END:VEVENT
+BEGIN:VEVENT
+UID:midnight
+DTSTART:20240102T000000
+END:VEVENT
+BEGIN:VEVENT
+UID:all-day
+DTSTART;VALUE=DATE:20240102
+END:VEVENT
+BEGIN:VEVENT
+UID:day-before
+DTSTART:20240101T090000
+END:VEVENT
+BEGIN:VEVENT
+UID:bad-start
+DTSTART:tomorrow
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"day-before", "all-day", "midnight", "afternoon", "no-start", "bad-start"}, eventIDs(cal.EventsSorted()))
+	assert.Equal(t, "no-start", cal.Events()[0].Id())
+}
+
+func TestEventsPage(t *testing.T) {
+	cal := NewCalendar()
+	for i, id := range []string{"c", "a", "d", "b", "e"} {
+		cal.AddEvent(id).SetStartAt(time.Date(2024, 1, 1+int(id[0]-'a'), 9, i, 0, 0, time.UTC))
+	}
+	assert.Equal(t, []string{"a", "b"}, eventIDs(cal.EventsPage(0, 2)))
+	assert.Equal(t, []string{"c", "d"}, eventIDs(cal.EventsPage(2, 2)))
+	assert.Equal(t, []string{"e"}, eventIDs(cal.EventsPage(4, 2)))
+	assert.Empty(t, cal.EventsPage(5, 2))
+	assert.Empty(t, cal.EventsPage(10, 2))
+	assert.Empty(t, cal.EventsPage(0, 0))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, eventIDs(cal.EventsPage(-1, 10)))
+	assert.Empty(t, cal.EventsPage(0, -1))
+}
+
+func TestAddRemoveTimezone(t *testing.T) {
+	cal := NewCalendar()
+	cal.AddEvent("event")
+	first := &VTimezone{}
+	first.SetProperty(ComponentProperty(PropertyTzid), "Fixed")
+	first.SetProperty(ComponentProperty(PropertyTzurl), "https://example.com/first")
+	cal.AddTimezone(first)
+	second := &VTimezone{}
+	second.SetProperty(ComponentProperty(PropertyTzid), "Fixed")
+	second.SetProperty(ComponentProperty(PropertyTzurl), "https://example.com/second")
+	cal.AddTimezone(second)
+
+	if assert.Len(t, cal.Timezones(), 1) {
+		assert.Same(t, second, cal.Timezones()[0])
+	}
+	s := cal.Serialize()
+	assert.True(t, strings.Index(s, "BEGIN:VTIMEZONE") < strings.Index(s, "BEGIN:VEVENT"), s)
+	assert.Equal(t, []string{"VEVENT", "VTIMEZONE"}, []string{componentName(cal.Components[0]), componentName(cal.Components[1])})
+
+	assert.True(t, cal.RemoveTimezone("Fixed"))
+	assert.False(t, cal.RemoveTimezone("Fixed"))
+	assert.Empty(t, cal.Timezones())
+	assert.Len(t, cal.Events(), 1)
+}
+
+func TestCalendarFreeBusy(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VEVENT
+UID:standup
+DTSTART:20240401T090000Z
+DTEND:20240401T091500Z
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+RECURRENCE-ID:20240402T090000Z
+DTSTART:20240402T093000Z
+DTEND:20240402T094500Z
+END:VEVENT
+BEGIN:VEVENT
+UID:review
+DTSTART:20240401T091500Z
+DURATION:PT45M
+END:VEVENT
+BEGIN:VEVENT
+UID:overlapping
+DTSTART:20240401T093000Z
+DTEND:20240401T094500Z
+END:VEVENT
+BEGIN:VEVENT
+UID:reminder
+DTSTART:20240401T120000Z
+DTEND:20240401T130000Z
+TRANSP:TRANSPARENT
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled
+DTSTART:20240401T140000Z
+DTEND:20240401T150000Z
+STATUS:CANCELLED
+END:VEVENT
+BEGIN:VEVENT
+UID:late
+DTSTART:20240403T230000Z
+DTEND:20240404T010000Z
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 4, day, hour, minute, 0, 0, time.UTC)
+	}
+	busy := cal.FreeBusy(at(1, 9, 5), at(4, 0, 0))
+	assert.Equal(t, []Period{
+		{Start: at(1, 9, 5), End: at(1, 10, 0)},
+		{Start: at(2, 9, 30), End: at(2, 9, 45)},
+		{Start: at(3, 9, 0), End: at(3, 9, 15)},
+		{Start: at(3, 23, 0), End: at(4, 0, 0)},
+	}, busy)
+	assert.Empty(t, cal.FreeBusy(at(5, 0, 0), at(6, 0, 0)))
+}
+
+func TestFreeBusyOverrides(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//Golang ICS Library
+BEGIN:VEVENT
+UID:sync
+DTSTART:20240401T090000Z
+DTEND:20240401T093000Z
+RRULE:FREQ=DAILY;COUNT=4
+END:VEVENT
+BEGIN:VEVENT
+UID:sync
+RECURRENCE-ID:20240402T090000Z
+DTSTART:20240402T100000Z
+DTEND:20240402T113000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:sync
+RECURRENCE-ID:20240403T090000Z
+DTSTART:20240403T090000Z
+DTEND:20240403T093000Z
+STATUS:CANCELLED
+END:VEVENT
+BEGIN:VEVENT
+UID:sync
+RECURRENCE-ID:20240404T090000Z
+DTSTART:20240404T090000Z
+DTEND:20240404T093000Z
+TRANSP:TRANSPARENT
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 4, day, hour, minute, 0, 0, time.UTC)
+	}
+	assert.Equal(t, []Period{
+		{Start: at(1, 9, 0), End: at(1, 9, 30)},
+		{Start: at(2, 10, 0), End: at(2, 11, 30)},
+	}, cal.FreeBusy(at(1, 0, 0), at(5, 0, 0)))
+	assert.Equal(t, []Period{
+		{Start: at(2, 11, 0), End: at(2, 11, 30)},
+	}, cal.FreeBusy(at(2, 11, 0), at(3, 0, 0)))
+}
+
+func TestRefreshInterval(t *testing.T) {
+	cal := NewCalendar()
+	_, err := cal.GetRefreshInterval()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	cal.SetRefreshInterval(time.Hour)
+	cal.SetRefreshInterval(12 * time.Hour)
+	assert.Contains(t, cal.Serialize(), "\r\nREFRESH-INTERVAL;VALUE=DURATION:PT12H\r\n")
+	assert.Equal(t, 1, strings.Count(cal.Serialize(), "REFRESH-INTERVAL"))
+
+	parsed, err := ParseCalendar(strings.NewReader(cal.Serialize()))
+	if assert.NoError(t, err) {
+		d, err := parsed.GetRefreshInterval()
+		assert.NoError(t, err)
+		assert.Equal(t, 12*time.Hour, d)
+	}
+}
+
+func TestColor(t *testing.T) {
+	c := NewCalendar()
+	_, err := c.GetColor()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	c.SetColor("turquoise")
+	color, err := c.GetColor()
+	assert.NoError(t, err)
+	assert.Equal(t, "turquoise", color)
+
+	e := c.AddEvent("color")
+	_, err = e.GetColor()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	e.SetColor("#40e0d0")
+	color, err = e.GetColor()
+	assert.NoError(t, err)
+	assert.Equal(t, "#40e0d0", color)
+	assert.Contains(t, c.Serialize(), "COLOR:#40e0d0\r\n")
+}
+
+func TestNameAndDescription(t *testing.T) {
+	c := NewCalendar()
+	_, err := c.GetName()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	_, err = c.GetDescription()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	c.SetName("Holidays, Public", WithLanguage("en"))
+	c.SetDescription("Public holidays; updated yearly")
+	s := c.Serialize()
+	assert.Contains(t, s, "NAME;LANGUAGE=en:Holidays\\, Public\r\n")
+	assert.Contains(t, s, "DESCRIPTION:Public holidays\\; updated yearly\r\n")
+
+	parsed, err := ParseCalendar(strings.NewReader(s))
+	if assert.NoError(t, err) {
+		name, err := parsed.GetName()
+		assert.NoError(t, err)
+		assert.Equal(t, "Holidays, Public", name)
+		description, err := parsed.GetDescription()
+		assert.NoError(t, err)
+		assert.Equal(t, "Public holidays; updated yearly", description)
+	}
+
+	c.SetCalendarDescription("Bank holidays, too")
+	description, err := c.GetCalendarDescription()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bank holidays, too", description)
+	description, err = c.GetDescription()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bank holidays, too", description)
+	assert.Contains(t, c.Serialize(), "DESCRIPTION:Bank holidays\\, too\r\n")
+}
+
+func TestSerializeMaxLineLength(t *testing.T) {
+	c := NewCalendar()
+	e := c.AddEvent("line-length")
+	description := strings.Repeat("A fairly long description of the event. ", 20)
+	e.SetDescription(description)
+
+	lines := func(s string) []string {
+		return strings.Split(strings.TrimSuffix(s, "\r\n"), "\r\n")
+	}
+	longest := func(s string) int {
+		n := 0
+		for _, l := range lines(s) {
+			if strings.HasPrefix(l, "BEGIN:") || strings.HasPrefix(l, "END:") {
+				continue
+			}
+			if len(l) > n {
+				n = len(l)
+			}
+		}
+		return n
+	}
+	assert.True(t, longest(c.Serialize()) <= 75)
+	assert.Equal(t, len("DESCRIPTION:"+description), longest(c.Serialize(WithMaxLineLength(900))))
+	assert.True(t, longest(c.Serialize(WithMaxLineLength(200))) <= 200)
+	assert.True(t, longest(c.Serialize(WithMaxLineLength(1))) <= 10)
+	e.SetDescription(description + description)
+	n := longest(c.Serialize(WithMaxLineLength(5000)))
+	assert.True(t, n > 900 && n <= 998, n)
+	e.SetDescription(description)
+
+	for _, n := range []int{1, 40, 200, 5000} {
+		parsed, err := ParseCalendar(strings.NewReader(c.Serialize(WithMaxLineLength(n))))
+		if assert.NoError(t, err, n) {
+			assert.Equal(t, description, parsed.Events()[0].GetProperty(ComponentPropertyDescription).Value, n)
+		}
+	}
+}
+
+func TestParsePropertyNameCase(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nversion:2.0\r\nProdId:-//test//EN\r\nBEGIN:VEVENT\r\nuid:1\r\n" +
+		"dtstamp:20240101T000000Z\r\nSummary:Lower case\r\nacknowledged:20240101T000000Z\r\nx-custom:yes\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input), WithStrictMode())
+	if !assert.NoError(t, err) {
+		return
+	}
+	version, err := cal.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0", version)
+	prodID, err := cal.GetProductId()
+	assert.NoError(t, err)
+	assert.Equal(t, "-//test//EN", prodID)
+	e := cal.Events()[0]
+	assert.Equal(t, "1", e.Id())
+	summary, err := e.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "Lower case", summary)
+	assert.NotNil(t, e.GetProperty(ComponentPropertyAcknowledged))
+	assert.NotNil(t, e.GetProperty(ComponentProperty("X-CUSTOM")))
+}
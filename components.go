@@ -2,10 +2,13 @@ package ics
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,12 +18,28 @@ import (
 type Component interface {
 	UnknownPropertiesIANAProperties() []IANAProperty
 	SubComponents() []Component
+	WalkProperties(fn func(Property, *IANAProperty) bool)
 	serialize(b io.Writer)
+	setCalendar(calendar *Calendar)
 }
 
+var (
+	// ErrPropertyNotFound is returned by getters when the component doesn't have the property. Other errors mean the
+	// property is present but its value can't be parsed.
+	ErrPropertyNotFound = errors.New("property not found")
+	// ErrAttendeeNotFound is returned when the component has no ATTENDEE with the email address.
+	ErrAttendeeNotFound = errors.New("attendee not found")
+)
+
 type ComponentBase struct {
 	Properties []IANAProperty
 	Components []Component
+	// calendar is the calendar the component belongs to, which TZIDs are resolved against
+	calendar *Calendar
+}
+
+func (cb *ComponentBase) setCalendar(calendar *Calendar) {
+	cb.calendar = calendar
 }
 
 func (cb *ComponentBase) UnknownPropertiesIANAProperties() []IANAProperty {
@@ -31,6 +50,16 @@ func (cb *ComponentBase) SubComponents() []Component {
 	return cb.Components
 }
 
+// WalkProperties calls fn with each property of the component in order, stopping early if fn returns false. The
+// properties may be modified in place.
+func (cb *ComponentBase) WalkProperties(fn func(Property, *IANAProperty) bool) {
+	for i := range cb.Properties {
+		if !fn(Property(cb.Properties[i].IANAToken), &cb.Properties[i]) {
+			return
+		}
+	}
+}
+
 func (cb ComponentBase) serializeThis(writer io.Writer, componentType string) {
 	fmt.Fprint(writer, "BEGIN:"+componentType, "\r\n")
 	for _, p := range cb.Properties {
@@ -61,6 +90,19 @@ func (cb *ComponentBase) GetProperty(componentProperty ComponentProperty) *IANAP
 	return nil
 }
 
+// GetPropertyOrDefault returns the property like GetProperty, or when the component doesn't have it, a property with
+// the default value which isn't added to the component.
+func (cb *ComponentBase) GetPropertyOrDefault(componentProperty ComponentProperty, defaultValue string) *IANAProperty {
+	if p := cb.GetProperty(componentProperty); p != nil {
+		return p
+	}
+	return &IANAProperty{BaseProperty{
+		IANAToken:      string(componentProperty),
+		ICalParameters: map[string][]string{},
+		Value:          defaultValue,
+	}}
+}
+
 func (cb *ComponentBase) SetProperty(property ComponentProperty, value string, props ...PropertyParameter) {
 	for i := range cb.Properties {
 		if cb.Properties[i].IANAToken == string(property) {
@@ -76,6 +118,84 @@ func (cb *ComponentBase) SetProperty(property ComponentProperty, value string, p
 	cb.AddProperty(property, value, props...)
 }
 
+// SetXProperty sets the vendor extension property with the name, which is given an "X-" prefix if it doesn't have
+// one. Existing instances of the property are replaced.
+func (cb *ComponentBase) SetXProperty(name string, value string, params map[string][]string) {
+	if !isXName(name) {
+		name = "X-" + name
+	}
+	r := cb.Properties[:0]
+	for _, p := range cb.Properties {
+		if !strings.EqualFold(p.IANAToken, name) {
+			r = append(r, p)
+		}
+	}
+	cb.Properties = r
+	p := IANAProperty{
+		BaseProperty{
+			IANAToken:      name,
+			Value:          value,
+			ICalParameters: map[string][]string{},
+		},
+	}
+	for k, v := range params {
+		p.ICalParameters[k] = v
+	}
+	cb.Properties = append(cb.Properties, p)
+}
+
+// GetXProperty returns the first vendor extension property with the name, compared case insensitively.
+func (cb *ComponentBase) GetXProperty(name string) (*IANAProperty, bool) {
+	for i := range cb.Properties {
+		if isXName(cb.Properties[i].IANAToken) && strings.EqualFold(cb.Properties[i].IANAToken, name) {
+			return &cb.Properties[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetXProperties returns the vendor extension properties of the component, those with an "X-" prefix, in order.
+func (cb *ComponentBase) GetXProperties() []*IANAProperty {
+	r := []*IANAProperty{}
+	for i := range cb.Properties {
+		if isXName(cb.Properties[i].IANAToken) {
+			r = append(r, &cb.Properties[i])
+		}
+	}
+	return r
+}
+
+func isXName(s string) bool {
+	return strings.HasPrefix(strings.ToUpper(s), "X-")
+}
+
+// RemoveProperty removes every instance of the property, returning how many were removed.
+func (cb *ComponentBase) RemoveProperty(property ComponentProperty) int {
+	r := cb.Properties[:0]
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(property) {
+			r = append(r, p)
+		}
+	}
+	n := len(cb.Properties) - len(r)
+	cb.Properties = r
+	return n
+}
+
+// RemovePropertyByValue removes the instances of a property with the value, such as one ATTENDEE of several, returning
+// how many were removed.
+func (cb *ComponentBase) RemovePropertyByValue(property ComponentProperty, value string) int {
+	r := cb.Properties[:0]
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(property) || p.Value != value {
+			r = append(r, p)
+		}
+	}
+	n := len(cb.Properties) - len(r)
+	cb.Properties = r
+	return n
+}
+
 func (cb *ComponentBase) AddProperty(property ComponentProperty, value string, props ...PropertyParameter) {
 	r := IANAProperty{
 		BaseProperty{
@@ -91,20 +211,6 @@ func (cb *ComponentBase) AddProperty(property ComponentProperty, value string, p
 	cb.Properties = append(cb.Properties, r)
 }
 
-type VEvent struct {
-	ComponentBase
-}
-
-func (c *VEvent) serialize(w io.Writer) {
-	c.ComponentBase.serializeThis(w, "VEVENT")
-}
-
-func (c *VEvent) Serialize() string {
-	b := &bytes.Buffer{}
-	c.ComponentBase.serializeThis(b, "VEVENT")
-	return b.String()
-}
-
 const (
 	icalTimestampFormatUtc   = "20060102T150405Z"
 	icalTimestampFormatLocal = "20060102T150405"
@@ -116,28 +222,1052 @@ var (
 	timeStampVariations = regexp.MustCompile("^([0-9]{8})?([TZ])?([0-9]{6})?(Z)?$")
 )
 
-func (event *VEvent) SetCreatedTime(t time.Time, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyCreated, t.UTC().Format(icalTimestampFormatUtc), props...)
+func (cb *ComponentBase) SetCreatedTime(t time.Time, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyCreated, t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+func (cb *ComponentBase) SetDtStampTime(t time.Time, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyDtstamp, t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+func (cb *ComponentBase) SetModifiedAt(t time.Time, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyLastModified, t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+func (cb *ComponentBase) GetCreatedTime() (time.Time, error) {
+	return cb.getTimeProp(ComponentPropertyCreated, false)
+}
+
+func (cb *ComponentBase) GetDtStampTime() (time.Time, error) {
+	return cb.getTimeProp(ComponentPropertyDtstamp, false)
+}
+
+func (cb *ComponentBase) GetModifiedAt() (time.Time, error) {
+	return cb.getTimeProp(ComponentPropertyLastModified, false)
+}
+
+func (cb *ComponentBase) SetSequence(seq int, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertySequence, strconv.Itoa(seq), props...)
+}
+
+// Priority is the PRIORITY of an event or to-do, from 1 for the highest to 9 for the lowest.
+type Priority int
+
+const (
+	PriorityUndefined Priority = 0
+	PriorityHigh      Priority = 1
+	PriorityMedium    Priority = 5
+	PriorityLow       Priority = 9
+)
+
+// SetPriority sets the PRIORITY of the component without checking it. SetPriorityLevel checks the priority is in range.
+func (cb *ComponentBase) SetPriority(p int, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyPriority, strconv.Itoa(p), props...)
+}
+
+// SetPriorityLevel sets the PRIORITY of the component, returning an error if it's outside 0 to 9.
+func (cb *ComponentBase) SetPriorityLevel(p Priority, props ...PropertyParameter) error {
+	if p < PriorityUndefined || p > PriorityLow {
+		return fmt.Errorf("priority %d out of range", p)
+	}
+	cb.SetProperty(ComponentPropertyPriority, strconv.Itoa(int(p)), props...)
+	return nil
+}
+
+// GetPriority returns the PRIORITY of the component.
+func (cb *ComponentBase) GetPriority() (Priority, error) {
+	p := cb.GetProperty(ComponentPropertyPriority)
+	if p == nil {
+		return PriorityUndefined, ErrPropertyNotFound
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(p.Value))
+	if err != nil {
+		return PriorityUndefined, fmt.Errorf("parsing %s: %w", ComponentPropertyPriority, err)
+	}
+	if n < int(PriorityUndefined) || n > int(PriorityLow) {
+		return PriorityUndefined, fmt.Errorf("priority %d out of range", n)
+	}
+	return Priority(n), nil
+}
+
+// GetSequence returns the SEQUENCE of the component, which is 0 when the property is missing or malformed.
+func (cb *ComponentBase) GetSequence() int {
+	p := cb.GetProperty(ComponentPropertySequence)
+	if p == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(p.Value))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// BumpSequence increments the SEQUENCE of the component, as an organizer must when making a significant change to it.
+// The parameters of the property are kept. It isn't safe for concurrent use; callers sharing a component between
+// goroutines must synchronise the read and the update themselves.
+func (cb *ComponentBase) BumpSequence() {
+	n := cb.GetSequence() + 1
+	if p := cb.GetProperty(ComponentPropertySequence); p != nil {
+		p.Value = strconv.Itoa(n)
+		return
+	}
+	cb.SetSequence(n)
+}
+
+func (cb *ComponentBase) SetStartAt(t time.Time, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyDtStart, t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+func (cb *ComponentBase) SetAllDayStartAt(t time.Time, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyDtStart, t.UTC().Format(icalDateFormatUtc), props...)
+}
+
+func (cb *ComponentBase) getTimeProp(componentProperty ComponentProperty, expectAllDay bool) (time.Time, error) {
+	timeProp := cb.GetProperty(componentProperty)
+	if timeProp == nil {
+		return time.Time{}, ErrPropertyNotFound
+	}
+
+	t, err := parseTimeValue(timeProp.BaseProperty.Value, timeProp.ICalParameters, expectAllDay, cb.calendar)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing %s: %w", componentProperty, err)
+	}
+	return t, nil
+}
+
+// parseTimeValue parses a DATE or DATE-TIME value. A TZID parameter is resolved against the calendar, which may be nil.
+func parseTimeValue(timeVal string, params map[string][]string, expectAllDay bool, calendar *Calendar) (time.Time, error) {
+	matched := timeStampVariations.FindStringSubmatch(timeVal)
+	if matched == nil {
+		return time.Time{}, fmt.Errorf("time value not matched, got '%s'", timeVal)
+	}
+	tOrZGrp := matched[2]
+	zGrp := matched[4]
+	grp1len := len(matched[1])
+	grp3len := len(matched[3])
+
+	tzId, tzIdOk := params[string(ParameterTzid)]
+	var propLoc *time.Location
+	if tzIdOk {
+		if len(tzId) != 1 {
+			return time.Time{}, errors.New("expected only one TZID")
+		}
+		var tzErr error
+		propLoc, tzErr = calendar.loadLocation(tzId[0])
+		if tzErr != nil {
+			return time.Time{}, tzErr
+		}
+	}
+	dateStr := matched[1]
+
+	if expectAllDay {
+		if grp1len > 0 {
+			if tOrZGrp == "Z" || zGrp == "Z" {
+				return time.ParseInLocation(icalDateFormatUtc, dateStr+"Z", time.UTC)
+			} else {
+				if propLoc == nil {
+					return time.ParseInLocation(icalDateFormatLocal, dateStr, time.Local)
+				} else {
+					return time.ParseInLocation(icalDateFormatLocal, dateStr, propLoc)
+				}
+			}
+		}
+
+		return time.Time{}, fmt.Errorf("time value matched but unsupported all-day timestamp, got '%s'", timeVal)
+	}
+
+	if grp1len > 0 && grp3len > 0 && tOrZGrp == "T" && zGrp == "Z" {
+		return time.ParseInLocation(icalTimestampFormatUtc, timeVal, time.UTC)
+	} else if grp1len > 0 && grp3len > 0 && tOrZGrp == "T" && zGrp == "" {
+		if propLoc == nil {
+			return time.ParseInLocation(icalTimestampFormatLocal, timeVal, time.Local)
+		} else {
+			return time.ParseInLocation(icalTimestampFormatLocal, timeVal, propLoc)
+		}
+	} else if grp1len > 0 && grp3len == 0 && tOrZGrp == "Z" && zGrp == "" {
+		return time.ParseInLocation(icalDateFormatUtc, dateStr+"Z", time.UTC)
+	} else if grp1len > 0 && grp3len == 0 && tOrZGrp == "" && zGrp == "" {
+		if propLoc == nil {
+			return time.ParseInLocation(icalDateFormatLocal, dateStr, time.Local)
+		} else {
+			return time.ParseInLocation(icalDateFormatLocal, dateStr, propLoc)
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("time value matched but not supported, got '%s'", timeVal)
+}
+
+// GetStartAt returns DTSTART, or ErrPropertyNotFound if it isn't set. Any other error means the value couldn't be
+// parsed, or its TZID couldn't be resolved.
+func (cb *ComponentBase) GetStartAt() (time.Time, error) {
+	return cb.getTimeProp(ComponentPropertyDtStart, false)
+}
+
+func (cb *ComponentBase) GetAllDayStartAt() (time.Time, error) {
+	return cb.getTimeProp(ComponentPropertyDtStart, true)
+}
+
+func (cb *ComponentBase) SetSummary(s string, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertySummary, ToText(s), props...)
+}
+
+// SetSummaryWithLanguage sets the SUMMARY along with the language it's written in, as a language tag such as "en-US".
+func (cb *ComponentBase) SetSummaryWithLanguage(s string, lang string, props ...PropertyParameter) {
+	cb.SetSummary(s, append(props, WithLanguage(lang))...)
+}
+
+// GetSummaryLanguage returns the LANGUAGE of the SUMMARY, which is empty if it doesn't have one, or
+// ErrPropertyNotFound if the SUMMARY isn't set.
+func (cb *ComponentBase) GetSummaryLanguage() (string, error) {
+	if cb.GetProperty(ComponentPropertySummary) == nil {
+		return "", ErrPropertyNotFound
+	}
+	return cb.getParameter(ComponentPropertySummary, ParameterLanguage), nil
+}
+
+// getParameter returns the first value of the parameter of the property, or an empty string if either is missing.
+func (cb *ComponentBase) getParameter(componentProperty ComponentProperty, parameter Parameter) string {
+	p := cb.GetProperty(componentProperty)
+	if p == nil {
+		return ""
+	}
+	if vs := p.ICalParameters[string(parameter)]; len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// GetSummary returns the SUMMARY of the component with its TEXT escapes removed, or ErrPropertyNotFound if it isn't
+// set.
+func (cb *ComponentBase) GetSummary() (string, error) {
+	return cb.getTextProp(ComponentPropertySummary)
+}
+
+// getTextProp returns the value of a TEXT property with its escapes removed.
+func (cb *ComponentBase) getTextProp(componentProperty ComponentProperty) (string, error) {
+	p := cb.GetProperty(componentProperty)
+	if p == nil {
+		return "", ErrPropertyNotFound
+	}
+	return p.textValue(), nil
+}
+
+func (cb *ComponentBase) SetStatus(s ObjectStatus, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyStatus, ToText(string(s)), props...)
+}
+
+// getStatus returns the STATUS of the component in upper case.
+func (cb *ComponentBase) getStatus() (string, error) {
+	p := cb.GetProperty(ComponentPropertyStatus)
+	if p == nil {
+		return "", ErrPropertyNotFound
+	}
+	return strings.ToUpper(FromText(p.Value)), nil
+}
+
+func (cb *ComponentBase) SetDescription(s string, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyDescription, ToText(s), props...)
+}
+
+// GetDescription returns the DESCRIPTION of the component with its TEXT escapes removed, or ErrPropertyNotFound if it
+// isn't set.
+func (cb *ComponentBase) GetDescription() (string, error) {
+	return cb.getTextProp(ComponentPropertyDescription)
+}
+
+func (cb *ComponentBase) SetLocation(s string, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyLocation, ToText(s), props...)
+}
+
+// GetLocation returns the LOCATION of the component with its TEXT escapes removed, or ErrPropertyNotFound if it isn't
+// set.
+func (cb *ComponentBase) GetLocation() (string, error) {
+	return cb.getTextProp(ComponentPropertyLocation)
+}
+
+// SetLocationWithAltRep sets the LOCATION along with a URI to an alternate representation of it, such as a geo: URI or
+// a link to a map.
+func (cb *ComponentBase) SetLocationWithAltRep(s string, altRep string, props ...PropertyParameter) {
+	cb.SetLocation(s, append(props, WithAltRep(altRep))...)
+}
+
+// StructuredLocation is the STRUCTURED-LOCATION of draft-ietf-calext-eventpub, a location given by a URI, such as a
+// geo: URI, along with its postal address.
+type StructuredLocation struct {
+	URI        string
+	Label      string
+	LocType    string
+	Street     string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+}
+
+// parameters pairs the parameters of a STRUCTURED-LOCATION with the fields holding them.
+func (l *StructuredLocation) parameters() []struct {
+	parameter Parameter
+	field     *string
+} {
+	return []struct {
+		parameter Parameter
+		field     *string
+	}{
+		{ParameterLabel, &l.Label},
+		{ParameterLoctype, &l.LocType},
+		{ParameterStreet, &l.Street},
+		{ParameterCity, &l.City},
+		{ParameterRegion, &l.Region},
+		{ParameterPostalCode, &l.PostalCode},
+		{ParameterCountry, &l.Country},
+	}
+}
+
+// SetStructuredLocation sets the STRUCTURED-LOCATION of the component. Empty fields are left out.
+func (cb *ComponentBase) SetStructuredLocation(l StructuredLocation, props ...PropertyParameter) {
+	props = append([]PropertyParameter{WithValue(string(ValueDataTypeUri))}, props...)
+	for _, f := range l.parameters() {
+		if *f.field != "" {
+			props = append(props, &KeyValues{Key: string(f.parameter), Value: []string{*f.field}})
+		}
+	}
+	cb.SetProperty(ComponentPropertyStructuredLocation, l.URI, props...)
+}
+
+// GetStructuredLocation returns the STRUCTURED-LOCATION of the component, or ErrPropertyNotFound if it doesn't have
+// one.
+func (cb *ComponentBase) GetStructuredLocation() (*StructuredLocation, error) {
+	p := cb.GetProperty(ComponentPropertyStructuredLocation)
+	if p == nil {
+		return nil, ErrPropertyNotFound
+	}
+	l := &StructuredLocation{URI: p.Value}
+	for _, f := range l.parameters() {
+		if vs := p.ICalParameters[string(f.parameter)]; len(vs) > 0 {
+			*f.field = strings.Join(vs, ",")
+		}
+	}
+	return l, nil
+}
+
+// GetLocationAltRep returns the ALTREP URI of the LOCATION, which is empty if it doesn't have one.
+func (cb *ComponentBase) GetLocationAltRep() (string, error) {
+	return cb.getAltRep(ComponentPropertyLocation)
+}
+
+// SetSummaryWithAltRep sets the SUMMARY along with a URI to an alternate representation of it, such as an HTML page.
+func (cb *ComponentBase) SetSummaryWithAltRep(s string, altRep string, props ...PropertyParameter) {
+	cb.SetSummary(s, append(props, WithAltRep(altRep))...)
+}
+
+// GetSummaryAltRep returns the ALTREP URI of the SUMMARY, which is empty if it doesn't have one.
+func (cb *ComponentBase) GetSummaryAltRep() (string, error) {
+	return cb.getAltRep(ComponentPropertySummary)
+}
+
+// SetDescriptionWithAltRep sets the DESCRIPTION along with a URI to an alternate representation of it, such as an
+// HTML rendering.
+func (cb *ComponentBase) SetDescriptionWithAltRep(s string, altRep string, props ...PropertyParameter) {
+	cb.SetDescription(s, append(props, WithAltRep(altRep))...)
+}
+
+// GetDescriptionAltRep returns the ALTREP URI of the DESCRIPTION, which is empty if it doesn't have one.
+func (cb *ComponentBase) GetDescriptionAltRep() (string, error) {
+	return cb.getAltRep(ComponentPropertyDescription)
+}
+
+// getAltRep returns the ALTREP URI of the property, or ErrPropertyNotFound if the property isn't set.
+func (cb *ComponentBase) getAltRep(componentProperty ComponentProperty) (string, error) {
+	if cb.GetProperty(componentProperty) == nil {
+		return "", ErrPropertyNotFound
+	}
+	return cb.getParameter(componentProperty, ParameterAltrep), nil
+}
+
+// SetGeo sets the GEO of the component. Floating point coordinates are written with 6 decimal places, which is precise
+// to within a metre.
+func (cb *ComponentBase) SetGeo(lat interface{}, lng interface{}, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyGeo, formatGeoCoordinate(lat)+";"+formatGeoCoordinate(lng), props...)
+}
+
+func formatGeoCoordinate(v interface{}) string {
+	switch f := v.(type) {
+	case float64:
+		return strconv.FormatFloat(f, 'f', 6, 64)
+	case float32:
+		return strconv.FormatFloat(float64(f), 'f', 6, 32)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// GetGeo returns the latitude and longitude given by the GEO of the component.
+func (cb *ComponentBase) GetGeo() (lat float64, lng float64, err error) {
+	p := cb.GetProperty(ComponentPropertyGeo)
+	if p == nil {
+		return 0, 0, ErrPropertyNotFound
+	}
+	parts := strings.Split(p.Value, ";")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("malformed %s '%s'", ComponentPropertyGeo, p.Value)
+	}
+	if lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+		return 0, 0, fmt.Errorf("parsing %s latitude: %w", ComponentPropertyGeo, err)
+	}
+	if lng, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		return 0, 0, fmt.Errorf("parsing %s longitude: %w", ComponentPropertyGeo, err)
+	}
+	return lat, lng, nil
+}
+
+// HasGeo reports whether the component has a GEO.
+func (cb *ComponentBase) HasGeo() bool {
+	return cb.GetProperty(ComponentPropertyGeo) != nil
+}
+
+// SetURL sets the URL of the component, returning an error if it isn't an absolute URI.
+func (cb *ComponentBase) SetURL(s string, props ...PropertyParameter) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", ComponentPropertyUrl, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("%s '%s' isn't an absolute URI", ComponentPropertyUrl, s)
+	}
+	cb.SetProperty(ComponentPropertyUrl, s, props...)
+	return nil
+}
+
+// GetURL returns the URL of the component, or ErrPropertyNotFound if it has none.
+func (cb *ComponentBase) GetURL() (string, error) {
+	p := cb.GetProperty(ComponentPropertyUrl)
+	if p == nil {
+		return "", ErrPropertyNotFound
+	}
+	return p.Value, nil
+}
+
+// GetURLParsed returns the URL of the component parsed, or ErrPropertyNotFound if it has none.
+func (cb *ComponentBase) GetURLParsed() (*url.URL, error) {
+	s, err := cb.GetURL()
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ComponentPropertyUrl, err)
+	}
+	return u, nil
+}
+
+// SetOrganizer sets the ORGANIZER to the email address, which may be given with or without the "mailto:" prefix.
+// WithCN and WithSentBy describe the organizer.
+func (cb *ComponentBase) SetOrganizer(s string, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyOrganizer, mailtoURI(s), props...)
+}
+
+// Organizer is the ORGANIZER of a component.
+type Organizer struct {
+	Email      string
+	CommonName string
+	SentBy     string
+}
+
+// GetOrganizer returns the ORGANIZER of the component, with the "mailto:" prefixes removed.
+func (cb *ComponentBase) GetOrganizer() (*Organizer, error) {
+	p := cb.GetProperty(ComponentPropertyOrganizer)
+	if p == nil {
+		return nil, ErrPropertyNotFound
+	}
+	o := &Organizer{Email: trimMailto(p.Value)}
+	if vs := p.ICalParameters[string(ParameterCn)]; len(vs) > 0 {
+		o.CommonName = vs[0]
+	}
+	if vs := p.ICalParameters[string(ParameterSentBy)]; len(vs) > 0 {
+		o.SentBy = trimMailto(vs[0])
+	}
+	return o, nil
+}
+
+// mailtoURI returns the CAL-ADDRESS of an email address.
+func mailtoURI(s string) string {
+	if strings.HasPrefix(strings.ToLower(s), "mailto:") {
+		return s
+	}
+	return "mailto:" + s
+}
+
+func trimMailto(s string) string {
+	if strings.HasPrefix(strings.ToLower(s), "mailto:") {
+		return s[len("mailto:"):]
+	}
+	return s
+}
+
+func (cb *ComponentBase) SetColor(s string, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyColor, s, props...)
+}
+
+// GetColor returns the COLOR of the component, or ErrPropertyNotFound if it isn't set.
+func (cb *ComponentBase) GetColor() (string, error) {
+	return cb.getTextProp(ComponentPropertyColor)
+}
+
+func (cb *ComponentBase) SetClass(c ObjectClass, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyClass, string(c), props...)
+}
+
+// AddComment adds a COMMENT to the component, alongside any it already has.
+func (cb *ComponentBase) AddComment(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyComment, ToText(s), props...)
 }
 
-func (event *VEvent) SetDtStampTime(t time.Time, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyDtstamp, t.UTC().Format(icalTimestampFormatUtc), props...)
+// GetComments returns the text of every COMMENT of the component, in order.
+func (cb *ComponentBase) GetComments() []string {
+	return cb.getTextValues(ComponentPropertyComment)
 }
 
-func (event *VEvent) SetModifiedAt(t time.Time, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyLastModified, t.UTC().Format(icalTimestampFormatUtc), props...)
+// ClearComments removes every COMMENT from the component.
+func (cb *ComponentBase) ClearComments() {
+	cb.RemoveProperty(ComponentPropertyComment)
+}
+
+// AddContact adds a CONTACT to the component, alongside any it already has.
+func (cb *ComponentBase) AddContact(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyContact, ToText(s), props...)
+}
+
+// AddContactWithAltRep adds a CONTACT along with a URI to an alternate representation of it, such as a vCard.
+func (cb *ComponentBase) AddContactWithAltRep(s string, altRep string, props ...PropertyParameter) {
+	cb.AddContact(s, append(props, WithAltRep(altRep))...)
+}
+
+// GetContacts returns the text of every CONTACT of the component, in order.
+func (cb *ComponentBase) GetContacts() []string {
+	return cb.getTextValues(ComponentPropertyContact)
+}
+
+// getTextValues returns the unescaped values of every instance of a TEXT property.
+func (cb *ComponentBase) getTextValues(property ComponentProperty) []string {
+	var r []string
+	for _, p := range cb.Properties {
+		if p.IANAToken == string(property) {
+			r = append(r, FromText(p.Value))
+		}
+	}
+	return r
+}
+
+// AddRelatedTo relates the component to the one with the UID. An empty relationship type is left out, which means
+// the other component is the parent.
+func (cb *ComponentBase) AddRelatedTo(uid string, relType RelationshipType, props ...PropertyParameter) {
+	if relType != "" {
+		props = append(props, relType)
+	}
+	cb.AddProperty(ComponentPropertyRelatedTo, ToText(uid), props...)
+}
+
+// RelatedEntry is a RELATED-TO of a component.
+type RelatedEntry struct {
+	UID     string
+	RelType RelationshipType
+}
+
+// GetRelatedTo returns every RELATED-TO of the component, in order. Relationships without a RELTYPE are to a parent.
+func (cb *ComponentBase) GetRelatedTo() []RelatedEntry {
+	var r []RelatedEntry
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(ComponentPropertyRelatedTo) {
+			continue
+		}
+		e := RelatedEntry{UID: FromText(p.Value), RelType: RelationshipTypeParent}
+		if vs := p.ICalParameters[string(ParameterReltype)]; len(vs) > 0 {
+			e.RelType = RelationshipType(strings.ToUpper(vs[0]))
+		}
+		r = append(r, e)
+	}
+	return r
+}
+
+// SetCategories replaces the CATEGORIES of the component with a single property listing every category.
+func (cb *ComponentBase) SetCategories(categories []string, props ...PropertyParameter) {
+	cb.RemoveProperty(ComponentPropertyCategories)
+	if len(categories) == 0 {
+		return
+	}
+	escaped := make([]string, len(categories))
+	for i, c := range categories {
+		escaped[i] = ToText(c)
+	}
+	cb.AddProperty(ComponentPropertyCategories, strings.Join(escaped, ","), props...)
+}
+
+// AddCategory appends a category to the first CATEGORIES property of the component, adding one if there is none. The
+// parameters given are set on the property, replacing any of the same name.
+func (cb *ComponentBase) AddCategory(category string, props ...PropertyParameter) {
+	for i := range cb.Properties {
+		p := &cb.Properties[i]
+		if p.IANAToken == string(ComponentPropertyCategories) {
+			if p.Value != "" {
+				p.Value += ","
+			}
+			p.Value += ToText(category)
+			for _, prop := range props {
+				if p.ICalParameters == nil {
+					p.ICalParameters = map[string][]string{}
+				}
+				k, v := prop.KeyValue()
+				p.ICalParameters[k] = v
+			}
+			return
+		}
+	}
+	cb.AddProperty(ComponentPropertyCategories, ToText(category), props...)
+}
+
+// GetCategories returns the categories listed by every CATEGORIES property of the component, unescaped. Categories
+// listed more than once are returned once, in the position they first appear.
+func (cb *ComponentBase) GetCategories() []string {
+	var r []string
+	seen := map[string]bool{}
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(ComponentPropertyCategories) || p.Value == "" {
+			continue
+		}
+		for _, c := range splitUnescaped(p.Value, ',') {
+			c = FromText(c)
+			if !seen[c] {
+				seen[c] = true
+				r = append(r, c)
+			}
+		}
+	}
+	return r
+}
+
+// GetClass returns the CLASS of the component in upper case. Components without a CLASS are public.
+func (cb *ComponentBase) GetClass() (ObjectClass, error) {
+	p := cb.GetProperty(ComponentPropertyClass)
+	if p == nil {
+		return ObjectClassPublic, ErrPropertyNotFound
+	}
+	return ObjectClass(strings.ToUpper(p.Value)), nil
+}
+
+// AddAttendee adds an ATTENDEE with the email address, which may be given with or without the "mailto:" prefix.
+// Parameters such as WithRole, WithPartStat, WithRSVP, WithCUType and WithCN describe the attendee. The returned
+// property is only valid until the next property is added to the component.
+func (cb *ComponentBase) AddAttendee(s string, props ...PropertyParameter) *IANAProperty {
+	cb.AddProperty(ComponentPropertyAttendee, mailtoURI(s), props...)
+	return &cb.Properties[len(cb.Properties)-1]
+}
+
+func (cb *ComponentBase) AddExdate(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyExdate, s, props...)
+}
+
+func (cb *ComponentBase) AddExrule(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyExrule, s, props...)
+}
+
+func (cb *ComponentBase) AddRdate(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyRdate, s, props...)
+}
+
+func (cb *ComponentBase) AddRrule(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyRrule, s, props...)
+}
+
+func (cb *ComponentBase) AddAttachment(s string, props ...PropertyParameter) {
+	cb.AddProperty(ComponentPropertyAttach, s, props...)
+}
+
+func (cb *ComponentBase) AddAttachmentURL(uri string, contentType string) {
+	cb.AddAttachment(uri, WithFmtType(contentType))
+}
+
+func (cb *ComponentBase) AddAttachmentBinary(binary []byte, contentType string) {
+	cb.AddAttachment(base64.StdEncoding.EncodeToString(binary),
+		WithFmtType(contentType), WithEncoding("BASE64"), WithValue(string(ValueDataTypeBinary)),
+	)
+}
+
+// Attachment is an ATTACH of a component, either a URI or inline data.
+type Attachment struct {
+	IsInline bool
+	URI      string
+	MimeType string
+	Data     []byte
+}
+
+// GetAttachments returns every ATTACH of the component, in order, with inline data decoded.
+func (cb *ComponentBase) GetAttachments() ([]Attachment, error) {
+	var r []Attachment
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(ComponentPropertyAttach) {
+			continue
+		}
+		a := Attachment{}
+		if vs := p.ICalParameters[string(ParameterFmttype)]; len(vs) > 0 {
+			a.MimeType = vs[0]
+		}
+		encoding := p.ICalParameters[string(ParameterEncoding)]
+		if p.valueDataType() == ValueDataTypeBinary || (len(encoding) > 0 && strings.EqualFold(encoding[0], "BASE64")) {
+			data, err := base64.StdEncoding.DecodeString(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("decoding %s: %w", ComponentPropertyAttach, err)
+			}
+			a.IsInline = true
+			a.Data = data
+		} else {
+			a.URI = p.Value
+		}
+		r = append(r, a)
+	}
+	return r, nil
+}
+
+// AddImage adds an IMAGE referring to an image at the URI, meant to be shown in each of the displays given. Empty
+// displays are left out, and an image without any is treated as BADGE, as RFC 7986 specifies.
+func (cb *ComponentBase) AddImage(uri, mimeType string, displays ...ImageDisplay) {
+	props := []PropertyParameter{WithValue(string(ValueDataTypeUri))}
+	if mimeType != "" {
+		props = append(props, WithFmtType(mimeType))
+	}
+	display := &KeyValues{Key: string(ParameterDisplay)}
+	for _, d := range displays {
+		if d != "" {
+			display.Value = append(display.Value, string(d))
+		}
+	}
+	if len(display.Value) > 0 {
+		props = append(props, display)
+	}
+	cb.AddProperty(ComponentPropertyImage, uri, props...)
+}
+
+// Image is an IMAGE of a component.
+type Image struct {
+	URI      string
+	MimeType string
+	Display  []ImageDisplay
+}
+
+// GetImages returns every IMAGE of the component given by a URI, in order. Inline images are skipped.
+func (cb *ComponentBase) GetImages() []Image {
+	var r []Image
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(ComponentPropertyImage) || p.valueDataType() != ValueDataTypeUri {
+			continue
+		}
+		img := Image{URI: p.Value}
+		if vs := p.ICalParameters[string(ParameterFmttype)]; len(vs) > 0 {
+			img.MimeType = vs[0]
+		}
+		for _, d := range p.ICalParameters[string(ParameterDisplay)] {
+			img.Display = append(img.Display, ImageDisplay(strings.ToUpper(d)))
+		}
+		if len(img.Display) == 0 {
+			img.Display = []ImageDisplay{ImageDisplayBadge}
+		}
+		r = append(r, img)
+	}
+	return r
 }
 
-func (event *VEvent) SetSequence(seq int, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertySequence, strconv.Itoa(seq), props...)
+// AddConference adds a CONFERENCE with the URI used to join it, such as a video call link or a "tel:" number.
+// WithFeature, WithLabel and WithLanguage describe the conference.
+func (cb *ComponentBase) AddConference(uri string, props ...PropertyParameter) {
+	props = append([]PropertyParameter{WithValue(string(ValueDataTypeUri))}, props...)
+	cb.AddProperty(ComponentPropertyConference, uri, props...)
+}
+
+// Conference is a CONFERENCE of a component.
+type Conference struct {
+	URI      string
+	Features []ConferenceFeature
+	Label    string
+	Language string
+}
+
+// GetConferences returns every CONFERENCE of the component, in order.
+func (cb *ComponentBase) GetConferences() []Conference {
+	var r []Conference
+	for _, p := range cb.Properties {
+		if p.IANAToken != string(ComponentPropertyConference) {
+			continue
+		}
+		c := Conference{URI: p.Value}
+		for _, f := range p.ICalParameters[string(ParameterFeature)] {
+			c.Features = append(c.Features, ConferenceFeature(strings.ToUpper(f)))
+		}
+		if vs := p.ICalParameters[string(ParameterLabel)]; len(vs) > 0 {
+			c.Label = vs[0]
+		}
+		if vs := p.ICalParameters[string(ParameterLanguage)]; len(vs) > 0 {
+			c.Language = vs[0]
+		}
+		r = append(r, c)
+	}
+	return r
+}
+
+// SetAttendeePartStat sets the PARTSTAT of the attendee with the email address, compared case insensitively.
+func (cb *ComponentBase) SetAttendeePartStat(email string, status ParticipationStatus) error {
+	p := cb.findAttendee(email)
+	if p == nil {
+		return fmt.Errorf("%w: %s", ErrAttendeeNotFound, email)
+	}
+	if p.ICalParameters == nil {
+		p.ICalParameters = map[string][]string{}
+	}
+	p.ICalParameters[string(ParameterParticipationStatus)] = []string{string(status)}
+	return nil
+}
+
+// GetAttendeePartStat returns the PARTSTAT of the attendee with the email address, compared case insensitively. An
+// attendee without one is NEEDS-ACTION, the RFC 5545 default.
+func (cb *ComponentBase) GetAttendeePartStat(email string) (ParticipationStatus, error) {
+	p := cb.findAttendee(email)
+	if p == nil {
+		return "", fmt.Errorf("%w: %s", ErrAttendeeNotFound, email)
+	}
+	if vs := p.ICalParameters[string(ParameterParticipationStatus)]; len(vs) > 0 {
+		return ParticipationStatus(strings.ToUpper(vs[0])), nil
+	}
+	return ParticipationStatusNeedsAction, nil
+}
+
+// GetAttendeeDelegatedTo returns the email addresses the attendee with the email address has delegated to, from its
+// DELEGATED-TO parameter.
+func (cb *ComponentBase) GetAttendeeDelegatedTo(email string) ([]string, error) {
+	return cb.getAttendeeAddresses(email, ParameterDelegatedTo)
+}
+
+// GetAttendeeDelegatedFrom returns the email addresses the attendee with the email address was delegated by, from its
+// DELEGATED-FROM parameter.
+func (cb *ComponentBase) GetAttendeeDelegatedFrom(email string) ([]string, error) {
+	return cb.getAttendeeAddresses(email, ParameterDelegatedFrom)
+}
+
+func (cb *ComponentBase) getAttendeeAddresses(email string, parameter Parameter) ([]string, error) {
+	p := cb.findAttendee(email)
+	if p == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAttendeeNotFound, email)
+	}
+	var r []string
+	for _, v := range p.ICalParameters[string(parameter)] {
+		r = append(r, trimMailto(v))
+	}
+	return r, nil
+}
+
+func (cb *ComponentBase) findAttendee(email string) *IANAProperty {
+	email = trimMailto(email)
+	for i := range cb.Properties {
+		p := &cb.Properties[i]
+		if p.IANAToken == string(ComponentPropertyAttendee) && strings.EqualFold(trimMailto(p.Value), email) {
+			return p
+		}
+	}
+	return nil
+}
+
+type Attendee struct {
+	IANAProperty
+}
+
+func (attendee *Attendee) Email() string {
+	return trimMailto(attendee.Value)
+}
+
+func (attendee *Attendee) ParticipationStatus() ParticipationStatus {
+	return ParticipationStatus(attendee.getPropertyFirst(ParameterParticipationStatus))
+}
+
+func (attendee *Attendee) CommonName() string {
+	return attendee.getPropertyFirst(ParameterCn)
+}
+
+func (attendee *Attendee) Role() ParticipationRole {
+	return ParticipationRole(attendee.getPropertyFirst(ParameterRole))
+}
+
+// RSVP reports whether a reply is requested from the attendee.
+func (attendee *Attendee) RSVP() bool {
+	return strings.EqualFold(attendee.getPropertyFirst(ParameterRsvp), "TRUE")
+}
+
+// CalendarUserType returns the CUTYPE of the attendee, INDIVIDUAL when it has none as RFC 5545 specifies.
+func (attendee *Attendee) CalendarUserType() CalendarUserType {
+	if cuType := attendee.getPropertyFirst(ParameterCutype); cuType != "" {
+		return CalendarUserType(strings.ToUpper(cuType))
+	}
+	return CalendarUserTypeIndividual
+}
+
+// ExtraParams returns the parameters of the attendee other than CN, ROLE, PARTSTAT, RSVP and CUTYPE.
+func (attendee *Attendee) ExtraParams() map[string][]string {
+	r := map[string][]string{}
+	for k, v := range attendee.ICalParameters {
+		switch Parameter(k) {
+		case ParameterCn, ParameterRole, ParameterParticipationStatus, ParameterRsvp, ParameterCutype:
+		default:
+			r[k] = v
+		}
+	}
+	return r
+}
+
+func (attendee *Attendee) getPropertyFirst(parameter Parameter) string {
+	vs := attendee.getProperty(parameter)
+	if len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+func (attendee *Attendee) getProperty(parameter Parameter) []string {
+	if vs, ok := attendee.ICalParameters[string(parameter)]; ok {
+		return vs
+	}
+	return nil
+}
+
+func (cb *ComponentBase) Attendees() (r []*Attendee) {
+	r = []*Attendee{}
+	for i := range cb.Properties {
+		switch cb.Properties[i].IANAToken {
+		case string(ComponentPropertyAttendee):
+			a := &Attendee{
+				cb.Properties[i],
+			}
+			r = append(r, a)
+		}
+	}
+	return
+}
+
+// GetAttendees returns the ATTENDEE properties of the component, the same as Attendees. The parameters are read with
+// the methods of Attendee, and changes to its parameters are kept by the component.
+func (cb *ComponentBase) GetAttendees() []*Attendee {
+	return cb.Attendees()
+}
+
+func (cb *ComponentBase) Id() string {
+	p := cb.GetProperty(ComponentPropertyUniqueId)
+	if p != nil {
+		return FromText(p.Value)
+	}
+	return ""
+}
+
+func (cb *ComponentBase) SetUID(uid string, props ...PropertyParameter) {
+	cb.SetProperty(ComponentPropertyUniqueId, ToText(uid), props...)
+}
+
+// GetUID returns the UID of the component, or ErrPropertyNotFound if it has none.
+func (cb *ComponentBase) GetUID() (string, error) {
+	return cb.getTextProp(ComponentPropertyUniqueId)
+}
+
+// newUID returns a random UUID based UID.
+func newUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d@golang-ical", time.Now().UnixNano())
+	}
+	// Version 4, variant 1 as in RFC 4122
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x@golang-ical", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func (cb *ComponentBase) addAlarm() *VAlarm {
+	a := &VAlarm{
+		ComponentBase: ComponentBase{},
+	}
+	cb.Components = append(cb.Components, a)
+	return a
+}
+
+func (cb *ComponentBase) alarms() (r []*VAlarm) {
+	r = []*VAlarm{}
+	for i := range cb.Components {
+		switch alarm := cb.Components[i].(type) {
+		case *VAlarm:
+			r = append(r, alarm)
+		}
+	}
+	return
+}
+
+type VEvent struct {
+	ComponentBase
+}
+
+func (c *VEvent) serialize(w io.Writer) {
+	var stamp time.Time
+	if ew, ok := w.(*errorWriter); ok {
+		stamp = ew.stamp
+	}
+	c.serialized(stamp).serializeThis(w, "VEVENT")
+}
+
+func (c *VEvent) Serialize() string {
+	b := &bytes.Buffer{}
+	c.serialized(time.Time{}).serializeThis(b, "VEVENT")
+	return b.String()
 }
 
-func (event *VEvent) SetStartAt(t time.Time, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyDtStart, t.UTC().Format(icalTimestampFormatUtc), props...)
+// serialized returns the component as it is written, which only differs from the event when a required property is
+// missing. An event without a UID, which RFC 5545 requires, is written with one derived from its content, so it is the
+// same each time the event is written; events from NewEvent and AddVEvent already have a UID, and those parsed without
+// one keep the derived UID until they are changed. An event without a SEQUENCE is written with SEQUENCE:0 as some
+// strict CalDAV servers expect one, and, unless stamp is zero, one without a DTSTAMP with stamp. The event itself is
+// left unchanged so it can be serialized concurrently.
+func (c *VEvent) serialized(stamp time.Time) ComponentBase {
+	cb := c.ComponentBase
+	uid := cb.GetProperty(ComponentPropertyUniqueId)
+	hasUID := uid != nil && uid.Value != ""
+	hasSequence := cb.GetProperty(ComponentPropertySequence) != nil
+	hasStamp := stamp.IsZero() || cb.GetProperty(ComponentPropertyDtstamp) != nil
+	if hasUID && hasSequence && hasStamp {
+		return cb
+	}
+	cb.Properties = make([]IANAProperty, 0, len(c.Properties)+3)
+	for _, p := range c.Properties {
+		if !hasUID && p.IANAToken == string(ComponentPropertyUniqueId) {
+			continue
+		}
+		cb.Properties = append(cb.Properties, p)
+	}
+	if !hasUID {
+		cb.Properties = append([]IANAProperty{{BaseProperty{
+			IANAToken: string(ComponentPropertyUniqueId),
+			Value:     contentUID(cb),
+		}}}, cb.Properties...)
+	}
+	if !hasStamp {
+		cb.Properties = append(cb.Properties, IANAProperty{BaseProperty{
+			IANAToken: string(ComponentPropertyDtstamp),
+			Value:     stamp.UTC().Format(icalTimestampFormatUtc),
+		}})
+	}
+	if !hasSequence {
+		cb.Properties = append(cb.Properties, IANAProperty{BaseProperty{
+			IANAToken: string(ComponentPropertySequence),
+			Value:     "0",
+		}})
+	}
+	return cb
 }
 
-func (event *VEvent) SetAllDayStartAt(t time.Time, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyDtStart, t.UTC().Format(icalDateFormatUtc), props...)
+// contentUID returns a UID derived from the serialized form of the component, which has no UID of its own.
+func contentUID(cb ComponentBase) string {
+	h := sha256.New()
+	cb.serializeThis(h, "VEVENT")
+	return fmt.Sprintf("%x@golang-ical", h.Sum(nil)[:16])
 }
 
 func (event *VEvent) SetEndAt(t time.Time, props ...PropertyParameter) {
@@ -148,109 +1278,116 @@ func (event *VEvent) SetAllDayEndAt(t time.Time, props ...PropertyParameter) {
 	event.SetProperty(ComponentPropertyDtEnd, t.UTC().Format(icalDateFormatUtc), props...)
 }
 
-// SetDuration updates the duration of an event.
-// This function will set either the end or start time of an event depending what is already given.
-// The duration defines the length of a event relative to start or end time.
-//
-// Notice: It will not set the DURATION key of the ics - only DTSTART and DTEND will be affected.
+// SetAllDay makes the event last the whole of the given date, taken in the date's own location, with a DATE valued
+// DTSTART and a DURATION of a day. Any DTEND is removed.
+func (event *VEvent) SetAllDay(date time.Time, props ...PropertyParameter) {
+	props = append(props, WithValue(string(ValueDataTypeDate)))
+	event.SetProperty(ComponentPropertyDtStart, date.Format(icalDateFormatLocal), props...)
+	event.RemoveProperty(ComponentPropertyDtEnd)
+	event.SetProperty(ComponentPropertyDuration, "P1D")
+}
+
+// SetDuration sets the DURATION of an event, replacing DTEND as RFC 5545 doesn't allow both. When the event only has an
+// end, the start is set to the end minus the duration so the event keeps its position.
 func (event *VEvent) SetDuration(d time.Duration) error {
-	t, err := event.GetStartAt()
-	if err == nil {
-		event.SetEndAt(t.Add(d))
-		return nil
-	} else {
-		t, err = event.GetEndAt()
-		if err == nil {
-			event.SetStartAt(t.Add(-d))
-			return nil
+	if d < 0 {
+		return errors.New("duration must not be negative")
+	}
+	if _, err := event.GetStartAt(); err != nil {
+		t, err := event.GetEndAt()
+		if err != nil {
+			return errors.New("start or end not yet defined")
 		}
+		event.SetStartAt(t.Add(-d))
 	}
-	return errors.New("start or end not yet defined")
+	event.RemoveProperty(ComponentPropertyDtEnd)
+	event.SetProperty(ComponentPropertyDuration, formatDuration(d))
+	return nil
 }
 
-func (event *VEvent) getTimeProp(componentProperty ComponentProperty, expectAllDay bool) (time.Time, error) {
-	timeProp := event.GetProperty(componentProperty)
-	if timeProp == nil {
-		return time.Time{}, errors.New("property not found")
+// GetDuration returns the DURATION of an event. It is an error for the event to have both a DURATION and a DTEND.
+func (event *VEvent) GetDuration() (time.Duration, error) {
+	p := event.GetProperty(ComponentPropertyDuration)
+	if p == nil {
+		return 0, ErrPropertyNotFound
 	}
-
-	timeVal := timeProp.BaseProperty.Value
-	matched := timeStampVariations.FindStringSubmatch(timeVal)
-	if matched == nil {
-		return time.Time{}, fmt.Errorf("time value not matched, got '%s'", timeVal)
+	if event.GetProperty(ComponentPropertyDtEnd) != nil {
+		return 0, errors.New("event has both DTEND and DURATION")
 	}
-	tOrZGrp := matched[2]
-	zGrp := matched[4]
-	grp1len := len(matched[1])
-	grp3len := len(matched[3])
+	return parseDuration(p.Value)
+}
 
-	tzId, tzIdOk := timeProp.ICalParameters["TZID"]
-	var propLoc *time.Location
-	if tzIdOk {
-		if len(tzId) != 1 {
-			return time.Time{}, errors.New("expected only one TZID")
-		}
-		var tzErr error
-		propLoc, tzErr = time.LoadLocation(tzId[0])
-		if tzErr != nil {
-			return time.Time{}, tzErr
-		}
+// Duration returns how long the event lasts, from its DURATION or otherwise from the difference between DTEND and
+// DTSTART. ErrPropertyNotFound is returned if the event has neither a DURATION nor both DTSTART and DTEND.
+func (event *VEvent) Duration() (time.Duration, error) {
+	if d, err := event.GetDuration(); err != ErrPropertyNotFound {
+		return d, err
 	}
-	dateStr := matched[1]
-
-	if expectAllDay {
-		if grp1len > 0 {
-			if tOrZGrp == "Z" || zGrp == "Z" {
-				return time.ParseInLocation(icalDateFormatUtc, dateStr+"Z", time.UTC)
-			} else {
-				if propLoc == nil {
-					return time.ParseInLocation(icalDateFormatLocal, dateStr, time.Local)
-				} else {
-					return time.ParseInLocation(icalDateFormatLocal, dateStr, propLoc)
-				}
-			}
-		}
-
-		return time.Time{}, fmt.Errorf("time value matched but unsupported all-day timestamp, got '%s'", timeVal)
+	end, err := event.GetEndAt()
+	if err != nil {
+		return 0, err
 	}
-
-	if grp1len > 0 && grp3len > 0 && tOrZGrp == "T" && zGrp == "Z" {
-		return time.ParseInLocation(icalTimestampFormatUtc, timeVal, time.UTC)
-	} else if grp1len > 0 && grp3len > 0 && tOrZGrp == "T" && zGrp == "" {
-		if propLoc == nil {
-			return time.ParseInLocation(icalTimestampFormatLocal, timeVal, time.Local)
-		} else {
-			return time.ParseInLocation(icalTimestampFormatLocal, timeVal, propLoc)
-		}
-	} else if grp1len > 0 && grp3len == 0 && tOrZGrp == "Z" && zGrp == "" {
-		return time.ParseInLocation(icalDateFormatUtc, dateStr+"Z", time.UTC)
-	} else if grp1len > 0 && grp3len == 0 && tOrZGrp == "" && zGrp == "" {
-		if propLoc == nil {
-			return time.ParseInLocation(icalDateFormatLocal, dateStr, time.Local)
-		} else {
-			return time.ParseInLocation(icalDateFormatLocal, dateStr, propLoc)
-		}
+	start, err := event.GetStartAt()
+	if err != nil {
+		return 0, err
 	}
-
-	return time.Time{}, fmt.Errorf("time value matched but not supported, got '%s'", timeVal)
-}
-
-func (event *VEvent) GetStartAt() (time.Time, error) {
-	return event.getTimeProp(ComponentPropertyDtStart, false)
+	return end.Sub(start), nil
 }
 
+// GetEndAt returns DTEND, or ErrPropertyNotFound if it isn't set. Any other error means the value couldn't be parsed,
+// or its TZID couldn't be resolved.
 func (event *VEvent) GetEndAt() (time.Time, error) {
 	return event.getTimeProp(ComponentPropertyDtEnd, false)
 }
 
-func (event *VEvent) GetAllDayStartAt() (time.Time, error) {
-	return event.getTimeProp(ComponentPropertyDtStart, true)
-}
-
 func (event *VEvent) GetAllDayEndAt() (time.Time, error) {
 	return event.getTimeProp(ComponentPropertyDtEnd, true)
 }
 
+// OverlapsWith reports whether the times of the two events overlap. The end of each event is its DTEND, or its start
+// plus its DURATION; without either an all-day event lasts the day and a timed event is an instant. Events starting
+// at the same time always overlap.
+func (event *VEvent) OverlapsWith(other *VEvent) (bool, error) {
+	start, end, err := event.timeRange()
+	if err != nil {
+		return false, err
+	}
+	otherStart, otherEnd, err := other.timeRange()
+	if err != nil {
+		return false, err
+	}
+	if start.Equal(otherStart) {
+		return true, nil
+	}
+	return start.Before(otherEnd) && otherStart.Before(end), nil
+}
+
+// timeRange returns the start and end of the event as described by OverlapsWith.
+func (event *VEvent) timeRange() (start time.Time, end time.Time, err error) {
+	start, err = event.GetStartAt()
+	if err != nil {
+		return start, end, err
+	}
+	end, err = event.GetEndAt()
+	if err != ErrPropertyNotFound {
+		return start, end, err
+	}
+	d, err := event.GetDuration()
+	switch {
+	case err == nil && event.IsAllDay() && d%(24*time.Hour) == 0:
+		// Days are nominal, so an all-day event keeps ending at midnight across daylight saving changes
+		return start, start.AddDate(0, 0, int(d/(24*time.Hour))), nil
+	case err == nil:
+		return start, start.Add(d), nil
+	case err != ErrPropertyNotFound:
+		return start, end, err
+	case event.IsAllDay():
+		return start, start.AddDate(0, 0, 1), nil
+	default:
+		return start, start, nil
+	}
+}
+
 type TimeTransparency string
 
 const (
@@ -262,187 +1399,236 @@ func (event *VEvent) SetTimeTransparency(v TimeTransparency, props ...PropertyPa
 	event.SetProperty(ComponentPropertyTransp, string(v), props...)
 }
 
-func (event *VEvent) SetSummary(s string, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertySummary, ToText(s), props...)
-}
-
-func (event *VEvent) SetStatus(s ObjectStatus, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyStatus, ToText(string(s)), props...)
+// GetTimeTransparency returns the TRANSP of the event. Events without one are opaque, and an unknown value is returned
+// along with an error.
+func (event *VEvent) GetTimeTransparency() (TimeTransparency, error) {
+	p := event.GetProperty(ComponentPropertyTransp)
+	if p == nil {
+		return TransparencyOpaque, ErrPropertyNotFound
+	}
+	switch v := TimeTransparency(strings.ToUpper(p.Value)); v {
+	case TransparencyOpaque, TransparencyTransparent:
+		return v, nil
+	default:
+		return v, fmt.Errorf("invalid %s '%s'", ComponentPropertyTransp, p.Value)
+	}
 }
 
-func (event *VEvent) SetDescription(s string, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyDescription, ToText(s), props...)
+// SetEventStatus sets the STATUS of the event, returning an error if the status doesn't apply to events. SetStatus
+// sets any status without checking it.
+func (event *VEvent) SetEventStatus(s EventStatus, props ...PropertyParameter) error {
+	if !s.valid() {
+		return fmt.Errorf("invalid %s status %s", ComponentVEvent, s)
+	}
+	event.SetProperty(ComponentPropertyStatus, string(s), props...)
+	return nil
 }
 
-func (event *VEvent) SetLocation(s string, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyLocation, ToText(s), props...)
+// GetStatus returns the STATUS of the event. A status which doesn't apply to events is returned along with an error.
+func (event *VEvent) GetStatus() (EventStatus, error) {
+	v, err := event.getStatus()
+	if err != nil {
+		return "", err
+	}
+	s := EventStatus(v)
+	if !s.valid() {
+		return s, fmt.Errorf("invalid %s status %s", ComponentVEvent, s)
+	}
+	return s, nil
 }
 
-func (event *VEvent) SetGeo(lat interface{}, lng interface{}, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyGeo, fmt.Sprintf("%v;%v", lat, lng), props...)
+func (event *VEvent) AddAlarm() *VAlarm {
+	return event.addAlarm()
 }
 
-func (event *VEvent) SetURL(s string, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyUrl, s, props...)
+func (event *VEvent) Alarms() (r []*VAlarm) {
+	return event.alarms()
 }
 
-func (event *VEvent) SetOrganizer(s string, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyOrganizer, s, props...)
+type VTodo struct {
+	ComponentBase
 }
 
-func (event *VEvent) SetColor(s string, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyColor, s, props...)
+func (c *VTodo) serialize(w io.Writer) {
+	c.ComponentBase.serializeThis(w, "VTODO")
 }
 
-func (event *VEvent) SetClass(c Classification, props ...PropertyParameter) {
-	event.SetProperty(ComponentPropertyClass, string(c), props...)
+func (c *VTodo) Serialize() string {
+	b := &bytes.Buffer{}
+	c.ComponentBase.serializeThis(b, "VTODO")
+	return b.String()
 }
 
-func (event *VEvent) AddAttendee(s string, props ...PropertyParameter) {
-	event.AddProperty(ComponentPropertyAttendee, "mailto:"+s, props...)
+func (todo *VTodo) SetCompletedAt(t time.Time, props ...PropertyParameter) {
+	todo.SetProperty(ComponentPropertyCompleted, t.UTC().Format(icalTimestampFormatUtc), props...)
 }
 
-func (event *VEvent) AddExdate(s string, props ...PropertyParameter) {
-	event.AddProperty(ComponentPropertyExdate, s, props...)
+func (todo *VTodo) SetAllDayCompletedAt(t time.Time, props ...PropertyParameter) {
+	props = append(props, WithValue(string(ValueDataTypeDate)))
+	todo.SetProperty(ComponentPropertyCompleted, t.Format(icalDateFormatLocal), props...)
 }
 
-func (event *VEvent) AddExrule(s string, props ...PropertyParameter) {
-	event.AddProperty(ComponentPropertyExrule, s, props...)
+func (todo *VTodo) SetDueAt(t time.Time, props ...PropertyParameter) {
+	todo.SetProperty(ComponentPropertyDue, t.UTC().Format(icalTimestampFormatUtc), props...)
 }
 
-func (event *VEvent) AddRdate(s string, props ...PropertyParameter) {
-	event.AddProperty(ComponentPropertyRdate, s, props...)
+func (todo *VTodo) SetAllDayDueAt(t time.Time, props ...PropertyParameter) {
+	props = append(props, WithValue(string(ValueDataTypeDate)))
+	todo.SetProperty(ComponentPropertyDue, t.Format(icalDateFormatLocal), props...)
 }
 
-func (event *VEvent) AddRrule(s string, props ...PropertyParameter) {
-	event.AddProperty(ComponentPropertyRrule, s, props...)
+func (todo *VTodo) SetPercentComplete(p int, props ...PropertyParameter) {
+	todo.SetProperty(ComponentPropertyPercentComplete, strconv.Itoa(p), props...)
 }
 
-func (event *VEvent) AddAttachment(s string, props ...PropertyParameter) {
-	event.AddProperty(ComponentPropertyAttach, s, props...)
+func (todo *VTodo) GetDueAt() (time.Time, error) {
+	return todo.getTimeProp(ComponentPropertyDue, false)
 }
 
-func (event *VEvent) AddAttachmentURL(uri string, contentType string) {
-	event.AddAttachment(uri, WithFmtType(contentType))
+func (todo *VTodo) GetAllDayDueAt() (time.Time, error) {
+	return todo.getTimeProp(ComponentPropertyDue, true)
 }
 
-func (event *VEvent) AddAttachmentBinary(binary []byte, contentType string) {
-	event.AddAttachment(base64.StdEncoding.EncodeToString(binary),
-		WithFmtType(contentType), WithEncoding("base64"), WithValue("binary"),
-	)
+func (todo *VTodo) GetCompletedAt() (time.Time, error) {
+	return todo.getTimeProp(ComponentPropertyCompleted, false)
 }
 
-type Attendee struct {
-	IANAProperty
+// SetTodoStatus sets the STATUS of the to-do, returning an error if the status doesn't apply to to-dos. SetStatus sets
+// any status without checking it.
+func (todo *VTodo) SetTodoStatus(s TodoStatus, props ...PropertyParameter) error {
+	if !s.valid() {
+		return fmt.Errorf("invalid %s status %s", ComponentVTodo, s)
+	}
+	todo.SetProperty(ComponentPropertyStatus, string(s), props...)
+	return nil
 }
 
-func (attendee *Attendee) Email() string {
-	if strings.HasPrefix(attendee.Value, "mailto:") {
-		return attendee.Value[len("mailto:"):]
+// GetStatus returns the STATUS of the to-do. A status which doesn't apply to to-dos is returned along with an error.
+func (todo *VTodo) GetStatus() (TodoStatus, error) {
+	v, err := todo.getStatus()
+	if err != nil {
+		return "", err
 	}
-	return attendee.Value
+	s := TodoStatus(v)
+	if !s.valid() {
+		return s, fmt.Errorf("invalid %s status %s", ComponentVTodo, s)
+	}
+	return s, nil
 }
 
-func (attendee *Attendee) ParticipationStatus() ParticipationStatus {
-	return ParticipationStatus(attendee.getPropertyFirst(ParameterParticipationStatus))
+func (todo *VTodo) AddAlarm() *VAlarm {
+	return todo.addAlarm()
 }
 
-func (attendee *Attendee) getPropertyFirst(parameter Parameter) string {
-	vs := attendee.getProperty(parameter)
-	if len(vs) > 0 {
-		return vs[0]
-	}
-	return ""
+func (todo *VTodo) Alarms() (r []*VAlarm) {
+	return todo.alarms()
 }
 
-func (attendee *Attendee) getProperty(parameter Parameter) []string {
-	if vs, ok := attendee.ICalParameters[string(parameter)]; ok {
-		return vs
-	}
-	return nil
+type VJournal struct {
+	ComponentBase
 }
 
-func (event *VEvent) Attendees() (r []*Attendee) {
-	r = []*Attendee{}
-	for i := range event.Properties {
-		switch event.Properties[i].IANAToken {
-		case string(ComponentPropertyAttendee):
-			a := &Attendee{
-				event.Properties[i],
-			}
-			r = append(r, a)
-		}
-	}
-	return
+func (c *VJournal) serialize(w io.Writer) {
+	c.ComponentBase.serializeThis(w, "VJOURNAL")
 }
 
-func (event *VEvent) Id() string {
-	p := event.GetProperty(ComponentPropertyUniqueId)
-	if p != nil {
-		return FromText(p.Value)
-	}
-	return ""
+func (c *VJournal) Serialize() string {
+	b := &bytes.Buffer{}
+	c.ComponentBase.serializeThis(b, "VJOURNAL")
+	return b.String()
 }
 
-func (event *VEvent) AddAlarm() *VAlarm {
-	a := &VAlarm{
-		ComponentBase: ComponentBase{},
+// SetJournalStatus sets the STATUS of the journal entry, returning an error if the status doesn't apply to journal
+// entries. SetStatus sets any status without checking it.
+func (c *VJournal) SetJournalStatus(s JournalStatus, props ...PropertyParameter) error {
+	if !s.valid() {
+		return fmt.Errorf("invalid %s status %s", ComponentVJournal, s)
 	}
-	event.Components = append(event.Components, a)
-	return a
+	c.SetProperty(ComponentPropertyStatus, string(s), props...)
+	return nil
 }
 
-func (event *VEvent) Alarms() (r []*VAlarm) {
-	r = []*VAlarm{}
-	for i := range event.Components {
-		switch alarm := event.Components[i].(type) {
-		case *VAlarm:
-			r = append(r, alarm)
-		}
+// GetStatus returns the STATUS of the journal entry. A status which doesn't apply to journal entries is returned along
+// with an error.
+func (c *VJournal) GetStatus() (JournalStatus, error) {
+	v, err := c.getStatus()
+	if err != nil {
+		return "", err
 	}
-	return
+	s := JournalStatus(v)
+	if !s.valid() {
+		return s, fmt.Errorf("invalid %s status %s", ComponentVJournal, s)
+	}
+	return s, nil
 }
 
-type VTodo struct {
+type VFreeBusy struct {
 	ComponentBase
 }
 
-func (c *VTodo) serialize(w io.Writer) {
-	c.ComponentBase.serializeThis(w, "VTODO")
-}
+// Deprecated: VBusy is the previous name of VFreeBusy.
+type VBusy = VFreeBusy
 
-func (c *VTodo) Serialize() string {
+func (c *VFreeBusy) Serialize() string {
 	b := &bytes.Buffer{}
-	c.ComponentBase.serializeThis(b, "VTODO")
+	c.ComponentBase.serializeThis(b, "VFREEBUSY")
 	return b.String()
 }
 
-type VJournal struct {
-	ComponentBase
-}
-
-func (c *VJournal) serialize(w io.Writer) {
-	c.ComponentBase.serializeThis(w, "VJOURNAL")
+func (c *VFreeBusy) serialize(w io.Writer) {
+	c.ComponentBase.serializeThis(w, "VFREEBUSY")
 }
 
-func (c *VJournal) Serialize() string {
-	b := &bytes.Buffer{}
-	c.ComponentBase.serializeThis(b, "VJOURNAL")
-	return b.String()
+func (fb *VFreeBusy) SetEndAt(t time.Time, props ...PropertyParameter) {
+	fb.SetProperty(ComponentPropertyDtEnd, t.UTC().Format(icalTimestampFormatUtc), props...)
 }
 
-type VBusy struct {
-	ComponentBase
+func (fb *VFreeBusy) GetEndAt() (time.Time, error) {
+	return fb.getTimeProp(ComponentPropertyDtEnd, false)
 }
 
-func (c *VBusy) Serialize() string {
-	b := &bytes.Buffer{}
-	c.ComponentBase.serializeThis(b, "VBUSY")
-	return b.String()
+// AddFreeBusy adds a FREEBUSY property listing the given periods. An empty fbType leaves out the FBTYPE parameter,
+// which means BUSY.
+func (fb *VFreeBusy) AddFreeBusy(fbType FreeBusyTimeType, periods ...Period) {
+	values := make([]string, len(periods))
+	for i := range periods {
+		values[i] = periods[i].String()
+	}
+	var props []PropertyParameter
+	if fbType != "" {
+		props = append(props, fbType)
+	}
+	fb.AddProperty(ComponentPropertyFreebusy, strings.Join(values, ","), props...)
 }
 
-func (c *VBusy) serialize(w io.Writer) {
-	c.ComponentBase.serializeThis(w, "VBUSY")
+// GetFreeBusyPeriods returns the periods of every FREEBUSY property, optionally limited to the given FBTYPE values.
+// FREEBUSY properties without an FBTYPE parameter are BUSY.
+func (fb *VFreeBusy) GetFreeBusyPeriods(fbTypes ...FreeBusyTimeType) ([]Period, error) {
+	r := []Period{}
+	for _, p := range fb.Properties {
+		if p.IANAToken != string(ComponentPropertyFreebusy) {
+			continue
+		}
+		if len(fbTypes) > 0 {
+			fbType := FreeBusyTimeTypeBusy
+			if v := p.ICalParameters[string(ParameterFbtype)]; len(v) > 0 {
+				fbType = FreeBusyTimeType(v[0])
+			}
+			match := false
+			for _, t := range fbTypes {
+				match = match || t == fbType
+			}
+			if !match {
+				continue
+			}
+		}
+		periods, err := ParsePeriods(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, periods...)
+	}
+	return r, nil
 }
 
 type VTimezone struct {
@@ -524,6 +1710,83 @@ func (alarm *VAlarm) SetTrigger(s string, props ...PropertyParameter) {
 	alarm.SetProperty(ComponentPropertyTrigger, s, props...)
 }
 
+// SetTriggerDuration sets a TRIGGER relative to the start of the parent component, or to its end when
+// AlarmTriggerRelationshipEnd is passed in props. Negative durations trigger before.
+func (alarm *VAlarm) SetTriggerDuration(d time.Duration, props ...PropertyParameter) {
+	alarm.SetProperty(ComponentPropertyTrigger, formatDuration(d), props...)
+}
+
+// SetTriggerTime sets a TRIGGER at an absolute point in time.
+func (alarm *VAlarm) SetTriggerTime(t time.Time, props ...PropertyParameter) {
+	props = append(props, WithValue(string(ValueDataTypeDateTime)))
+	alarm.SetProperty(ComponentPropertyTrigger, t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+func (alarm *VAlarm) SetDuration(d time.Duration, props ...PropertyParameter) {
+	alarm.SetProperty(ComponentPropertyDuration, formatDuration(d), props...)
+}
+
+func (alarm *VAlarm) SetRepeat(n int, props ...PropertyParameter) {
+	alarm.SetProperty(ComponentPropertyRepeat, strconv.Itoa(n), props...)
+}
+
+func (alarm *VAlarm) GetAction() Action {
+	return Action(alarm.GetPropertyValue(PropertyAction))
+}
+
+// AlarmTrigger is the parsed value of a TRIGGER property. Relative triggers carry an offset in Duration from the start
+// or end of the parent component, as given by Related; absolute triggers carry Time.
+type AlarmTrigger struct {
+	Absolute bool
+	Duration time.Duration
+	Time     time.Time
+	Related  AlarmTriggerRelationship
+}
+
+func (alarm *VAlarm) GetTrigger() (*AlarmTrigger, error) {
+	p := alarm.GetProperty(ComponentPropertyTrigger)
+	if p == nil {
+		return nil, ErrPropertyNotFound
+	}
+	if v := p.ICalParameters[string(ParameterValue)]; (len(v) > 0 && v[0] == string(ValueDataTypeDateTime)) || !strings.Contains(p.Value, "P") {
+		t, err := parseTimeValue(p.Value, p.ICalParameters, false, alarm.calendar)
+		if err != nil {
+			return nil, err
+		}
+		return &AlarmTrigger{Absolute: true, Time: t}, nil
+	}
+	d, err := parseDuration(p.Value)
+	if err != nil {
+		return nil, err
+	}
+	r := &AlarmTrigger{Duration: d, Related: AlarmTriggerRelationshipStart}
+	if v := p.ICalParameters[string(ParameterRelated)]; len(v) > 0 {
+		switch related := AlarmTriggerRelationship(strings.ToUpper(v[0])); related {
+		case AlarmTriggerRelationshipStart, AlarmTriggerRelationshipEnd:
+			r.Related = related
+		default:
+			return nil, fmt.Errorf("unknown trigger relationship '%s'", v[0])
+		}
+	}
+	return r, nil
+}
+
+func (alarm *VAlarm) GetDuration() (time.Duration, error) {
+	p := alarm.GetProperty(ComponentPropertyDuration)
+	if p == nil {
+		return 0, ErrPropertyNotFound
+	}
+	return parseDuration(p.Value)
+}
+
+func (alarm *VAlarm) GetRepeat() (int, error) {
+	p := alarm.GetProperty(ComponentPropertyRepeat)
+	if p == nil {
+		return 0, ErrPropertyNotFound
+	}
+	return strconv.Atoi(p.Value)
+}
+
 type Standard struct {
 	ComponentBase
 }
@@ -621,6 +1884,28 @@ func (o *VTimezoneObservance) GetTzOffsetTo() string {
 	return o.GetPropertyValue(PropertyTzoffsetto)
 }
 
+// GetTzOffsetFromDuration returns TZOFFSETFROM as a signed offset from UTC, positive east of UTC.
+func (o *VTimezoneObservance) GetTzOffsetFromDuration() (time.Duration, error) {
+	return o.getTzOffsetDuration(PropertyTzoffsetfrom)
+}
+
+// GetTzOffsetToDuration returns TZOFFSETTO as a signed offset from UTC, positive east of UTC.
+func (o *VTimezoneObservance) GetTzOffsetToDuration() (time.Duration, error) {
+	return o.getTzOffsetDuration(PropertyTzoffsetto)
+}
+
+func (o *VTimezoneObservance) getTzOffsetDuration(property Property) (time.Duration, error) {
+	p := o.GetProperty(ComponentProperty(property))
+	if p == nil {
+		return 0, ErrPropertyNotFound
+	}
+	offset, err := parseUtcOffset(p.Value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(offset) * time.Second, nil
+}
+
 func (o *VTimezoneObservance) GetTzName() string {
 	return o.GetPropertyValue(PropertyTzname)
 }
@@ -648,31 +1933,92 @@ func (c *GeneralComponent) serialize(w io.Writer) {
 	c.ComponentBase.serializeThis(w, c.Token)
 }
 
+// componentName returns the name used in the BEGIN and END lines of the component.
+func componentName(c Component) string {
+	switch c := c.(type) {
+	case *VEvent:
+		return string(ComponentVEvent)
+	case *VTodo:
+		return string(ComponentVTodo)
+	case *VJournal:
+		return string(ComponentVJournal)
+	case *VFreeBusy:
+		return string(ComponentVFreeBusy)
+	case *VTimezone:
+		return string(ComponentVTimezone)
+	case *VAlarm:
+		return string(ComponentVAlarm)
+	case *Standard:
+		return string(ComponentStandard)
+	case *Daylight:
+		return string(ComponentDaylight)
+	case *GeneralComponent:
+		return c.Token
+	}
+	return ""
+}
+
+// newComponent wraps already parsed properties and sub components in the type matching the component name.
+func newComponent(name string, cb ComponentBase) Component {
+	switch ComponentType(strings.ToUpper(name)) {
+	case ComponentVEvent:
+		return &VEvent{cb}
+	case ComponentVTodo:
+		return &VTodo{cb}
+	case ComponentVJournal:
+		return &VJournal{cb}
+	case ComponentVFreeBusy:
+		return &VFreeBusy{cb}
+	case ComponentVTimezone:
+		return &VTimezone{cb}
+	case ComponentVAlarm:
+		return &VAlarm{cb}
+	case ComponentStandard:
+		return &Standard{cb}
+	case ComponentDaylight:
+		return &Daylight{cb}
+	}
+	return &GeneralComponent{ComponentBase: cb, Token: strings.ToUpper(name)}
+}
+
+// cloneComponent returns a deep copy of the component and its sub components, not yet belonging to a calendar.
+func cloneComponent(c Component) Component {
+	cb := ComponentBase{}
+	if props := c.UnknownPropertiesIANAProperties(); props != nil {
+		cb.Properties = make([]IANAProperty, 0, len(props))
+		for i := range props {
+			cb.Properties = append(cb.Properties, IANAProperty{props[i].clone()})
+		}
+	}
+	for _, sub := range c.SubComponents() {
+		cb.Components = append(cb.Components, cloneComponent(sub))
+	}
+	if gc, ok := c.(*GeneralComponent); ok {
+		return &GeneralComponent{ComponentBase: cb, Token: gc.Token}
+	}
+	return newComponent(componentName(c), cb)
+}
+
+// componentProperty returns the first instance of the property on the component, or nil.
+func componentProperty(c Component, property Property) *IANAProperty {
+	props := c.UnknownPropertiesIANAProperties()
+	for i := range props {
+		if props[i].IANAToken == string(property) {
+			return &props[i]
+		}
+	}
+	return nil
+}
+
 func GeneralParseComponent(cs *CalendarStream, startLine *BaseProperty) (Component, error) {
-	var co Component
-	switch startLine.Value {
-	case "VCALENDAR":
+	if startLine.Value == "VCALENDAR" {
 		return nil, errors.New("malformed calendar; vcalendar not where expected")
-	case "VEVENT":
-		co = ParseVEvent(cs, startLine)
-	case "VTODO":
-		co = ParseVTodo(cs, startLine)
-	case "VJOURNAL":
-		co = ParseVJournal(cs, startLine)
-	case "VFREEBUSY":
-		co = ParseVBusy(cs, startLine)
-	case "VTIMEZONE":
-		co = ParseVTimezone(cs, startLine)
-	case "VALARM":
-		co = ParseVAlarm(cs, startLine)
-	case "STANDARD":
-		co = ParseStandard(cs, startLine)
-	case "DAYLIGHT":
-		co = ParseDaylight(cs, startLine)
-	default:
-		co = ParseGeneralComponent(cs, startLine)
 	}
-	return co, nil
+	cb, err := ParseComponent(cs, startLine)
+	if err != nil {
+		return nil, err
+	}
+	return newComponent(startLine.Value, cb), nil
 }
 
 func ParseVEvent(cs *CalendarStream, startLine *BaseProperty) *VEvent {
@@ -708,17 +2054,22 @@ func ParseVJournal(cs *CalendarStream, startLine *BaseProperty) *VJournal {
 	return rr
 }
 
-func ParseVBusy(cs *CalendarStream, startLine *BaseProperty) *VBusy {
+func ParseVFreeBusy(cs *CalendarStream, startLine *BaseProperty) *VFreeBusy {
 	r, err := ParseComponent(cs, startLine)
 	if err != nil {
 		return nil
 	}
-	rr := &VBusy{
+	rr := &VFreeBusy{
 		ComponentBase: r,
 	}
 	return rr
 }
 
+// Deprecated: use ParseVFreeBusy.
+func ParseVBusy(cs *CalendarStream, startLine *BaseProperty) *VBusy {
+	return ParseVFreeBusy(cs, startLine)
+}
+
 func ParseVTimezone(cs *CalendarStream, startLine *BaseProperty) *VTimezone {
 	r, err := ParseComponent(cs, startLine)
 	if err != nil {
@@ -778,14 +2129,14 @@ func ParseGeneralComponent(cs *CalendarStream, startLine *BaseProperty) *General
 func ParseComponent(cs *CalendarStream, startLine *BaseProperty) (ComponentBase, error) {
 	cb := ComponentBase{}
 	cont := true
-	for ln := 0; cont; ln++ {
+	for cont {
 		l, err := cs.ReadLine()
 		if err != nil {
 			switch err {
 			case io.EOF:
 				cont = false
 			default:
-				return cb, err
+				return cb, cs.parseError(err)
 			}
 		}
 		if l == nil || len(*l) == 0 {
@@ -793,10 +2144,10 @@ func ParseComponent(cs *CalendarStream, startLine *BaseProperty) (ComponentBase,
 		}
 		line, err := ParseProperty(*l)
 		if err != nil {
-			return cb, fmt.Errorf("parsing component property %d: %w", ln, err)
+			return cb, cs.parseError(err)
 		}
 		if line == nil {
-			return cb, errors.New("parsing component line")
+			return cb, cs.parseError(errors.New("parsing component line"))
 		}
 		switch line.IANAToken {
 		case "END":
@@ -804,12 +2155,12 @@ func ParseComponent(cs *CalendarStream, startLine *BaseProperty) (ComponentBase,
 			case startLine.Value:
 				return cb, nil
 			default:
-				return cb, errors.New("unbalanced end")
+				return cb, cs.parseError(errors.New("unbalanced end"))
 			}
 		case "BEGIN":
 			co, err := GeneralParseComponent(cs, line)
 			if err != nil {
-				return cb, err
+				return cb, cs.parseError(err)
 			}
 			if co != nil {
 				cb.Components = append(cb.Components, co)
@@ -818,5 +2169,5 @@ func ParseComponent(cs *CalendarStream, startLine *BaseProperty) (ComponentBase,
 			cb.Properties = append(cb.Properties, IANAProperty{*line})
 		}
 	}
-	return cb, errors.New("ran out of lines")
+	return cb, cs.parseError(errors.New("ran out of lines"))
 }
@@ -1,6 +1,8 @@
 package ics
 
 import (
+	"errors"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -24,7 +26,8 @@ func TestSetDuration(t *testing.T) {
 			output: `BEGIN:VEVENT
 UID:test-duration
 DTSTART:20060102T150400Z
-DTEND:20060102T170400Z
+DURATION:PT2H
+SEQUENCE:0
 END:VEVENT
 `,
 		},
@@ -33,8 +36,9 @@ END:VEVENT
 			end:  date,
 			output: `BEGIN:VEVENT
 UID:test-duration
-DTEND:20060102T150400Z
 DTSTART:20060102T130400Z
+DURATION:PT2H
+SEQUENCE:0
 END:VEVENT
 `,
 		},
@@ -59,3 +63,1041 @@ END:VEVENT
 		})
 	}
 }
+
+func TestGetDuration(t *testing.T) {
+	e := NewEvent("test-duration")
+	e.SetStartAt(time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC))
+	assert.Error(t, e.SetDuration(-time.Hour))
+	assert.NoError(t, e.SetDuration(90*time.Minute))
+	d, err := e.GetDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	e.SetEndAt(time.Date(2006, 1, 2, 17, 4, 0, 0, time.UTC))
+	_, err = e.GetDuration()
+	assert.Error(t, err)
+
+	assert.Error(t, NewEvent("test-duration").SetDuration(time.Hour))
+	_, err = NewEvent("test-duration").GetDuration()
+	assert.Error(t, err)
+}
+
+func TestEventDuration(t *testing.T) {
+	e := NewEvent("test-duration")
+	_, err := e.Duration()
+	assert.Equal(t, ErrPropertyNotFound, err)
+
+	e.SetStartAt(time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC))
+	_, err = e.Duration()
+	assert.Equal(t, ErrPropertyNotFound, err)
+
+	e.SetEndAt(time.Date(2006, 1, 2, 17, 4, 0, 0, time.UTC))
+	d, err := e.Duration()
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, d)
+
+	e.RemoveProperty(ComponentPropertyDtEnd)
+	assert.NoError(t, e.SetDuration(45*time.Minute))
+	d, err = e.Duration()
+	assert.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, d)
+}
+
+func TestGetStartEndAtErrors(t *testing.T) {
+	e := NewEvent("test-errors")
+	_, err := e.GetStartAt()
+	assert.Equal(t, ErrPropertyNotFound, err)
+	_, err = e.GetEndAt()
+	assert.Equal(t, ErrPropertyNotFound, err)
+
+	e.SetProperty(ComponentPropertyDtStart, "tomorrow")
+	e.SetProperty(ComponentPropertyDtEnd, "20210101T100000", &KeyValues{Key: string(ParameterTzid), Value: []string{"Nowhere/Unknown"}})
+	_, err = e.GetStartAt()
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrPropertyNotFound, err)
+	_, err = e.GetEndAt()
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrPropertyNotFound))
+}
+
+func TestTodo(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//ABC Corporation//NONSGML My Product//EN
+BEGIN:VTODO
+DTSTAMP:19980130T134500Z
+SEQUENCE:2
+UID:uid4@example.com
+ORGANIZER:mailto:unclesam@example.com
+ATTENDEE;PARTSTAT=ACCEPTED:mailto:jqpublic@example.com
+DUE:19980415T000000Z
+STATUS:NEEDS-ACTION
+SUMMARY:Submit Income Taxes
+BEGIN:VALARM
+ACTION:AUDIO
+TRIGGER:19980403T120000Z
+END:VALARM
+END:VTODO
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	todos := cal.Todos()
+	if !assert.Len(t, todos, 1) {
+		return
+	}
+	todo := todos[0]
+	assert.Equal(t, "uid4@example.com", todo.Id())
+	due, err := todo.GetDueAt()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(1998, 4, 15, 0, 0, 0, 0, time.UTC), due)
+	assert.Len(t, todo.Alarms(), 1)
+	assert.Len(t, todo.Attendees(), 1)
+	assert.Equal(t, input, strings.Replace(cal.Serialize(), "\r\n", "\n", -1))
+
+	cal = NewCalendar()
+	todo = cal.AddTodo("uid5@example.com")
+	todo.SetSummary("Renew passport")
+	todo.SetDueAt(time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC))
+	todo.SetCompletedAt(time.Date(2022, 4, 28, 9, 30, 0, 0, time.UTC))
+	todo.SetStatus(ObjectStatusCompleted)
+	todo.SetPriority(1)
+	todo.SetPercentComplete(100)
+	todo.AddAlarm().SetAction(ActionDisplay)
+	assert.Equal(t, `BEGIN:VTODO
+UID:uid5@example.com
+SUMMARY:Renew passport
+DUE:20220501T120000Z
+COMPLETED:20220428T093000Z
+STATUS:COMPLETED
+PRIORITY:1
+PERCENT-COMPLETE:100
+BEGIN:VALARM
+ACTION:DISPLAY
+END:VALARM
+END:VTODO
+`, strings.Replace(todo.Serialize(), "\r\n", "\n", -1))
+}
+
+func TestJournal(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//ABC Corporation//NONSGML My Product//EN
+BEGIN:VJOURNAL
+DTSTAMP:19970324T120000Z
+UID:uid5@example.com
+ORGANIZER:mailto:jsmith@example.com
+STATUS:DRAFT
+CLASS:PUBLIC
+CATEGORIES:Project Report,XYZ,Weekly Meeting
+DESCRIPTION:Project xyz Review Meeting Minutes\nAgenda
+END:VJOURNAL
+BEGIN:VJOURNAL
+DTSTAMP:19970331T120000Z
+UID:uid6@example.com
+DTSTART;VALUE=DATE:19970331
+SUMMARY:Follow up
+STATUS:FINAL
+CLASS:PRIVATE
+RELATED-TO:uid5@example.com
+END:VJOURNAL
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	journals := cal.Journals()
+	if !assert.Len(t, journals, 2) {
+		return
+	}
+	assert.Equal(t, "uid5@example.com", journals[0].Id())
+	assert.Equal(t, "uid5@example.com", journals[1].GetPropertyValue(PropertyRelatedTo))
+	assert.Equal(t, input, strings.Replace(cal.Serialize(), "\r\n", "\n", -1))
+
+	cal = NewCalendar()
+	journal := cal.AddJournal("uid7@example.com")
+	journal.SetSummary("Retro notes")
+	journal.SetDescription("Went well, mostly")
+	journal.SetClass(ObjectClassConfidential)
+	journal.SetJournalStatus(JournalStatusDraft)
+	assert.Equal(t, `BEGIN:VJOURNAL
+UID:uid7@example.com
+SUMMARY:Retro notes
+DESCRIPTION:Went well\, mostly
+CLASS:CONFIDENTIAL
+STATUS:DRAFT
+END:VJOURNAL
+`, strings.Replace(journal.Serialize(), "\r\n", "\n", -1))
+}
+
+func TestFreeBusy(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//RDU Software//NONSGML HandCal//EN
+BEGIN:VFREEBUSY
+ORGANIZER:mailto:jsmith@example.com
+DTSTART:19980313T141711Z
+DTEND:19980410T141711Z
+FREEBUSY:19980314T233000Z/19980315T003000Z
+FREEBUSY;FBTYPE=BUSY-TENTATIVE:19980316T153000Z/PT1H,19980318T030000Z/PT1H
+URL:http://www.example.com/calendar/busytime/jsmith.ifb
+END:VFREEBUSY
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	blocks := cal.FreeBusyBlocks()
+	if !assert.Len(t, blocks, 1) {
+		return
+	}
+	periods, err := blocks[0].GetFreeBusyPeriods()
+	assert.NoError(t, err)
+	assert.Equal(t, []Period{
+		{time.Date(1998, 3, 14, 23, 30, 0, 0, time.UTC), time.Date(1998, 3, 15, 0, 30, 0, 0, time.UTC)},
+		{time.Date(1998, 3, 16, 15, 30, 0, 0, time.UTC), time.Date(1998, 3, 16, 16, 30, 0, 0, time.UTC)},
+		{time.Date(1998, 3, 18, 3, 0, 0, 0, time.UTC), time.Date(1998, 3, 18, 4, 0, 0, 0, time.UTC)},
+	}, periods)
+	periods, err = blocks[0].GetFreeBusyPeriods(FreeBusyTimeTypeBusy)
+	assert.NoError(t, err)
+	assert.Len(t, periods, 1)
+	assert.Equal(t, input, strings.Replace(cal.Serialize(), "\r\n", "\n", -1))
+
+	fb := NewFreeBusy("fb1@example.com")
+	fb.SetStartAt(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	fb.SetEndAt(time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC))
+	fb.AddFreeBusy(FreeBusyTimeTypeBusyUnavailable, Period{time.Date(2022, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)})
+	assert.Equal(t, `BEGIN:VFREEBUSY
+UID:fb1@example.com
+DTSTART:20220101T000000Z
+DTEND:20220102T000000Z
+FREEBUSY;FBTYPE=BUSY-UNAVAILABLE:20220101T090000Z/20220101T100000Z
+END:VFREEBUSY
+`, strings.Replace(fb.Serialize(), "\r\n", "\n", -1))
+}
+
+func TestAlarm(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//ABC Corporation//NONSGML My Product//EN
+BEGIN:VEVENT
+UID:alarm1@example.com
+DTSTART:19980403T120000Z
+SEQUENCE:0
+BEGIN:VALARM
+ACTION:AUDIO
+TRIGGER;VALUE=DATE-TIME:19970317T133000Z
+REPEAT:4
+DURATION:PT15M
+ATTACH;FMTTYPE=audio/basic:ftp://example.com/pub/sounds/bell-01.aud
+END:VALARM
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER;RELATED=END:-PT30M
+DESCRIPTION:Breakfast meeting with executive\nteam at 8:30 AM EST.
+END:VALARM
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	alarms := cal.Events()[0].Alarms()
+	if !assert.Len(t, alarms, 2) {
+		return
+	}
+	assert.Equal(t, ActionAudio, alarms[0].GetAction())
+	trigger, err := alarms[0].GetTrigger()
+	assert.NoError(t, err)
+	assert.Equal(t, &AlarmTrigger{Absolute: true, Time: time.Date(1997, 3, 17, 13, 30, 0, 0, time.UTC)}, trigger)
+	repeat, err := alarms[0].GetRepeat()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, repeat)
+	d, err := alarms[0].GetDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, d)
+
+	trigger, err = alarms[1].GetTrigger()
+	assert.NoError(t, err)
+	assert.Equal(t, &AlarmTrigger{Duration: -30 * time.Minute, Related: AlarmTriggerRelationshipEnd}, trigger)
+	assert.Equal(t, input, strings.Replace(cal.Serialize(), "\r\n", "\n", -1))
+
+	e := NewEvent("alarm2@example.com")
+	a := e.AddAlarm()
+	a.SetAction(ActionDisplay)
+	a.SetTriggerDuration(-15 * time.Minute)
+	a.SetDescription("Reminder")
+	a = e.AddAlarm()
+	a.SetAction(ActionEmail)
+	a.SetTriggerTime(time.Date(2022, 1, 1, 8, 0, 0, 0, time.UTC))
+	a.SetSummary("Email reminder")
+	a.AddAttendee("someone@example.com")
+	assert.Equal(t, `BEGIN:VEVENT
+UID:alarm2@example.com
+SEQUENCE:0
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Reminder
+END:VALARM
+BEGIN:VALARM
+ACTION:EMAIL
+TRIGGER;VALUE=DATE-TIME:20220101T080000Z
+SUMMARY:Email reminder
+ATTENDEE:mailto:someone@example.com
+END:VALARM
+END:VEVENT
+`, strings.Replace(e.Serialize(), "\r\n", "\n", -1))
+}
+
+func TestGeneratedUID(t *testing.T) {
+	cal := NewCalendar()
+	e := NewEvent("")
+	cal.AddVEvent(e)
+	cal.AddVEvent(&VEvent{})
+	output := cal.Serialize()
+	uids := regexp.MustCompile(`UID:([0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}@golang-ical)\r\n`).FindAllStringSubmatch(output, -1)
+	if assert.Len(t, uids, 2, output) {
+		assert.NotEqual(t, uids[0][1], uids[1][1])
+		uid, err := e.GetUID()
+		assert.NoError(t, err)
+		assert.Equal(t, uids[0][1], uid)
+	}
+	assert.Equal(t, output, cal.Serialize())
+
+	e.SetUID("explicit@example.com")
+	uid, err := e.GetUID()
+	assert.NoError(t, err)
+	assert.Equal(t, "explicit@example.com", uid)
+	assert.Contains(t, e.Serialize(), "UID:explicit@example.com\r\n")
+
+	e.SetUID("")
+	assert.Regexp(t, `^BEGIN:VEVENT\r\nUID:[0-9a-f-]+@golang-ical\r\n`, e.Serialize())
+	uid, err = e.GetUID()
+	assert.NoError(t, err)
+	assert.Equal(t, "", uid)
+	e.RemoveProperty(ComponentPropertyUniqueId)
+	assert.Regexp(t, `^BEGIN:VEVENT\r\nUID:[0-9a-f-]+@golang-ical\r\n`, e.Serialize())
+	assert.Nil(t, e.GetProperty(ComponentPropertyUniqueId))
+	_, err = e.GetUID()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+}
+
+func TestParsedEventWithoutUID(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nSUMMARY:No UID\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:Other\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	output := cal.Serialize()
+	assert.Equal(t, output, cal.Serialize())
+	uids := regexp.MustCompile(`UID:([0-9a-f]{32}@golang-ical)\r\n`).FindAllStringSubmatch(output, -1)
+	if assert.Len(t, uids, 2, output) {
+		assert.NotEqual(t, uids[0][1], uids[1][1])
+	}
+	assert.Nil(t, cal.Events()[0].GetProperty(ComponentPropertyUniqueId))
+
+	reparsed, err := ParseCalendar(strings.NewReader(output))
+	if assert.NoError(t, err) {
+		assert.Equal(t, output, reparsed.Serialize())
+	}
+}
+
+func TestAddAttendee(t *testing.T) {
+	e := NewEvent("test-attendee")
+	p := e.AddAttendee("alice@example.com", WithRole(ParticipationRoleChair), WithPartStat(ParticipationStatusAccepted),
+		WithRSVP(true), WithCUType(CalendarUserTypeIndividual), WithCN("Alice"))
+	assert.Equal(t, "mailto:alice@example.com", p.Value)
+	assert.Equal(t, map[string][]string{
+		"ROLE":     {"CHAIR"},
+		"PARTSTAT": {"ACCEPTED"},
+		"RSVP":     {"true"},
+		"CUTYPE":   {"INDIVIDUAL"},
+		"CN":       {"Alice"},
+	}, p.ICalParameters)
+	p = e.AddAttendee("MAILTO:bob@example.com")
+	p.ICalParameters["X-NUM-GUESTS"] = []string{"0"}
+	assert.Contains(t, e.Serialize(), "ATTENDEE;X-NUM-GUESTS=0:MAILTO:bob@example.com\r\n")
+	assert.Len(t, e.Attendees(), 2)
+}
+
+func TestAttendees(t *testing.T) {
+	input := `BEGIN:VEVENT
+UID:test-attendees
+ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=TENTATIVE;RSVP=TRUE;CUTYPE=GROUP;CN=Team;X-NUM-GUESTS=0:mailto:team@example.com
+ATTENDEE:mailto:bob@example.com
+END:VEVENT
+`
+	cal, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\n" + input + "END:VCALENDAR\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	attendees := cal.Events()[0].Attendees()
+	if !assert.Len(t, attendees, 2) {
+		return
+	}
+	assert.Equal(t, attendees, cal.Events()[0].GetAttendees())
+	a := attendees[0]
+	assert.Equal(t, "team@example.com", a.Email())
+	assert.Equal(t, "Team", a.CommonName())
+	assert.Equal(t, ParticipationRoleReqParticipant, a.Role())
+	assert.Equal(t, ParticipationStatusTentative, a.ParticipationStatus())
+	assert.True(t, a.RSVP())
+	assert.Equal(t, CalendarUserTypeGroup, a.CalendarUserType())
+	assert.Equal(t, map[string][]string{"X-NUM-GUESTS": {"0"}}, a.ExtraParams())
+	b := attendees[1]
+	assert.Equal(t, "bob@example.com", b.Email())
+	assert.False(t, b.RSVP())
+	assert.Equal(t, ParticipationRole(""), b.Role())
+	assert.Equal(t, CalendarUserTypeIndividual, b.CalendarUserType())
+	assert.Empty(t, b.ExtraParams())
+	b.ICalParameters = map[string][]string{string(ParameterCutype): {"room"}}
+	assert.Equal(t, CalendarUserTypeRoom, b.CalendarUserType())
+}
+
+func TestAttendeeDelegation(t *testing.T) {
+	input := `BEGIN:VEVENT
+UID:test-delegation
+ATTENDEE;PARTSTAT=DELEGATED;DELEGATED-TO="mailto:bob@example.com","mailto:carol@example.com":mailto:alice@example.com
+ATTENDEE;DELEGATED-FROM="mailto:alice@example.com":mailto:bob@example.com
+ATTENDEE:mailto:dave@example.com
+END:VEVENT
+`
+	cal, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\n" + input + "END:VCALENDAR\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	e := cal.Events()[0]
+	to, err := e.GetAttendeeDelegatedTo("alice@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob@example.com", "carol@example.com"}, to)
+	from, err := e.GetAttendeeDelegatedFrom("bob@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice@example.com"}, from)
+	to, err = e.GetAttendeeDelegatedTo("dave@example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, to)
+	_, err = e.GetAttendeeDelegatedFrom("erin@example.com")
+	assert.True(t, errors.Is(err, ErrAttendeeNotFound))
+}
+
+func TestOrganizer(t *testing.T) {
+	e := NewEvent("test-organizer")
+	_, err := e.GetOrganizer()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+
+	e.SetOrganizer("boss@example.com", WithSentBy("assistant@example.com"))
+	o, err := e.GetOrganizer()
+	assert.NoError(t, err)
+	assert.Equal(t, &Organizer{Email: "boss@example.com", SentBy: "assistant@example.com"}, o)
+	assert.Contains(t, e.Serialize(), "ORGANIZER;SENT-BY=\"mailto:assistant@example.com\":mailto:boss@example.com\r\n")
+	e.SetOrganizer("mailto:boss@example.com", WithCN("Boss"))
+	assert.Contains(t, e.Serialize(), "ORGANIZER;CN=Boss:mailto:boss@example.com\r\n")
+
+	cal, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\n" +
+		"ORGANIZER;CN=Boss;SENT-BY=\"mailto:assistant@example.com\":mailto:boss@example.com\n" +
+		"END:VEVENT\nEND:VCALENDAR\n"))
+	if assert.NoError(t, err) {
+		o, err := cal.Events()[0].GetOrganizer()
+		assert.NoError(t, err)
+		assert.Equal(t, &Organizer{Email: "boss@example.com", CommonName: "Boss", SentBy: "assistant@example.com"}, o)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	e := NewEvent("test-status")
+	_, err := e.GetStatus()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	assert.NoError(t, e.SetEventStatus(EventStatusCancelled))
+	assert.Error(t, e.SetEventStatus(EventStatus("DRAFT")))
+	status, err := e.GetStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, EventStatusCancelled, status)
+	e.SetStatus(ObjectStatusCompleted)
+	status, err = e.GetStatus()
+	assert.Error(t, err)
+	assert.Equal(t, EventStatus("COMPLETED"), status)
+
+	todo := NewCalendar().AddTodo("test-todo-status")
+	assert.NoError(t, todo.SetTodoStatus(TodoStatusInProcess))
+	assert.Error(t, todo.SetTodoStatus(TodoStatus("FINAL")))
+	todoStatus, err := todo.GetStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, TodoStatusInProcess, todoStatus)
+
+	journal := NewCalendar().AddJournal("test-journal-status")
+	assert.NoError(t, journal.SetJournalStatus(JournalStatusFinal))
+	assert.Error(t, journal.SetJournalStatus(JournalStatus("CONFIRMED")))
+	journalStatus, err := journal.GetStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, JournalStatusFinal, journalStatus)
+}
+
+func TestClass(t *testing.T) {
+	e := NewEvent("test-class")
+	class, err := e.GetClass()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	assert.Equal(t, ObjectClassPublic, class)
+	e.SetClass(ObjectClassPrivate)
+	assert.Contains(t, e.Serialize(), "CLASS:PRIVATE\r\n")
+	e.SetProperty(ComponentPropertyClass, "confidential")
+	class, err = e.GetClass()
+	assert.NoError(t, err)
+	assert.Equal(t, ObjectClassConfidential, class)
+}
+
+func TestClassOnTodoAndJournal(t *testing.T) {
+	for _, c := range []interface {
+		SetClass(ObjectClass, ...PropertyParameter)
+		GetClass() (ObjectClass, error)
+		Serialize() string
+	}{NewTodo("test-todo-class"), NewJournal("test-journal-class")} {
+		class, err := c.GetClass()
+		assert.True(t, errors.Is(err, ErrPropertyNotFound))
+		assert.Equal(t, ObjectClassPublic, class)
+		c.SetClass(ObjectClassPrivate)
+		assert.Contains(t, c.Serialize(), "CLASS:PRIVATE\r\n")
+		class, err = c.GetClass()
+		assert.NoError(t, err)
+		assert.Equal(t, ObjectClassPrivate, class)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	e := NewEvent("test-categories")
+	assert.Empty(t, e.GetCategories())
+	e.AddCategory("Work")
+	e.AddCategory("Meetings, all hands")
+	assert.Contains(t, e.Serialize(), "CATEGORIES:Work,Meetings\\, all hands\r\n")
+	assert.Equal(t, []string{"Work", "Meetings, all hands"}, e.GetCategories())
+	e.AddProperty(ComponentPropertyCategories, "Travel,Work")
+	assert.Equal(t, []string{"Work", "Meetings, all hands", "Travel"}, e.GetCategories())
+	e.SetCategories([]string{"Home", "a;b"})
+	assert.Equal(t, []string{"Home", "a;b"}, e.GetCategories())
+	assert.Contains(t, e.Serialize(), "CATEGORIES:Home,a\\;b\r\n")
+	e.SetCategories(nil)
+	assert.Nil(t, e.GetProperty(ComponentPropertyCategories))
+
+	e.SetProperty(ComponentPropertyCategories, "")
+	e.AddCategory("Sport", WithLanguage("en"))
+	assert.Contains(t, e.Serialize(), "CATEGORIES;LANGUAGE=en:Sport\r\n")
+	e.AddCategory("Fußball", WithLanguage("de"))
+	assert.Contains(t, e.Serialize(), "CATEGORIES;LANGUAGE=de:Sport,Fußball\r\n")
+}
+
+func TestXProperties(t *testing.T) {
+	e := NewEvent("test-x-properties")
+	e.SetXProperty("X-APPLE-TRAVEL-ADVISORY-BEHAVIOR", "AUTOMATIC", nil)
+	e.SetXProperty("MICROSOFT-CDO-BUSYSTATUS", "BUSY", map[string][]string{"X-SOURCE": {"outlook"}})
+	e.SetXProperty("x-apple-travel-advisory-behavior", "DISABLED", nil)
+	e.SetSummary("Not an extension")
+
+	p, ok := e.GetXProperty("X-MICROSOFT-CDO-BUSYSTATUS")
+	if assert.True(t, ok) {
+		assert.Equal(t, "BUSY", p.Value)
+		assert.Equal(t, []string{"outlook"}, p.ICalParameters["X-SOURCE"])
+	}
+	_, ok = e.GetXProperty("SUMMARY")
+	assert.False(t, ok)
+	var names []string
+	for _, p := range e.GetXProperties() {
+		names = append(names, p.IANAToken+":"+p.Value)
+	}
+	assert.Equal(t, []string{"X-MICROSOFT-CDO-BUSYSTATUS:BUSY", "x-apple-travel-advisory-behavior:DISABLED"}, names)
+}
+
+func TestSequence(t *testing.T) {
+	e := NewEvent("test-sequence")
+	assert.Equal(t, 0, e.GetSequence())
+	assert.Contains(t, e.Serialize(), "SEQUENCE:0\r\n")
+	assert.Nil(t, e.GetProperty(ComponentPropertySequence))
+	e.BumpSequence()
+	assert.Equal(t, 1, e.GetSequence())
+	e.SetSequence(4)
+	e.BumpSequence()
+	assert.Equal(t, 5, e.GetSequence())
+	assert.Contains(t, e.Serialize(), "SEQUENCE:5\r\n")
+	e.SetProperty(ComponentPropertySequence, "abc")
+	assert.Equal(t, 0, e.GetSequence())
+}
+
+func TestCreatedAndModifiedTimes(t *testing.T) {
+	e := NewEvent("test-times")
+	_, err := e.GetCreatedTime()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", 2*60*60))
+	modified := time.Date(2024, 3, 2, 11, 30, 0, 0, time.UTC)
+	e.SetCreatedTime(created)
+	e.SetDtStampTime(modified)
+	e.SetModifiedAt(modified)
+	assert.Contains(t, e.Serialize(), "CREATED:20240301T080000Z\r\n")
+	assert.Contains(t, e.Serialize(), "LAST-MODIFIED:20240302T113000Z\r\n")
+	for name, get := range map[string]func() (time.Time, error){
+		"created":  e.GetCreatedTime,
+		"dtstamp":  e.GetDtStampTime,
+		"modified": e.GetModifiedAt,
+	} {
+		v, err := get()
+		expected := modified
+		if name == "created" {
+			expected = created
+		}
+		if assert.NoError(t, err, name) {
+			assert.True(t, expected.Equal(v), name)
+		}
+	}
+}
+
+func TestRemoveProperty(t *testing.T) {
+	e := NewEvent("test-remove")
+	e.AddAttendee("alice@example.com")
+	e.AddAttendee("bob@example.com")
+	e.AddAttendee("alice@example.com", WithRole(ParticipationRoleChair))
+	e.SetSummary("Planning")
+
+	assert.Equal(t, 2, e.RemovePropertyByValue(ComponentPropertyAttendee, "mailto:alice@example.com"))
+	assert.Len(t, e.Attendees(), 1)
+	assert.Equal(t, 0, e.RemovePropertyByValue(ComponentPropertyAttendee, "mailto:carol@example.com"))
+	assert.Equal(t, 1, e.RemoveProperty(ComponentPropertyAttendee))
+	assert.Equal(t, 0, e.RemoveProperty(ComponentPropertyAttendee))
+	assert.Equal(t, "Planning", e.GetProperty(ComponentPropertySummary).Value)
+}
+
+func TestGetPropertyOrDefault(t *testing.T) {
+	e := NewEvent("test-default")
+	p := e.GetPropertyOrDefault(ComponentPropertySummary, "Untitled")
+	assert.Equal(t, "Untitled", p.Value)
+	assert.Equal(t, string(ComponentPropertySummary), p.IANAToken)
+	assert.Nil(t, e.GetProperty(ComponentPropertySummary))
+
+	e.SetSummary("Planning")
+	assert.Equal(t, "Planning", e.GetPropertyOrDefault(ComponentPropertySummary, "Untitled").Value)
+	e.GetPropertyOrDefault(ComponentPropertySummary, "Untitled").Value = "Changed"
+	assert.Equal(t, "Changed", e.GetProperty(ComponentPropertySummary).Value)
+}
+
+func TestSummaryAndDescriptionAltRep(t *testing.T) {
+	e := NewEvent("test-altrep")
+	_, err := e.GetSummaryAltRep()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	_, err = e.GetDescriptionAltRep()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+
+	e.SetSummary("Planning")
+	altRep, err := e.GetSummaryAltRep()
+	assert.NoError(t, err)
+	assert.Equal(t, "", altRep)
+
+	e.SetSummaryWithAltRep("Planning", "https://example.com/planning")
+	e.SetDescriptionWithAltRep("Quarterly planning", "cid:part1.0001@example.org")
+	s := e.Serialize()
+	assert.Contains(t, s, "SUMMARY;ALTREP=\"https://example.com/planning\":Planning\r\n")
+	assert.Contains(t, s, "DESCRIPTION;ALTREP=\"cid:part1.0001@example.org\":Quarterly planning\r\n")
+	altRep, err = e.GetSummaryAltRep()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/planning", altRep)
+	altRep, err = e.GetDescriptionAltRep()
+	assert.NoError(t, err)
+	assert.Equal(t, "cid:part1.0001@example.org", altRep)
+}
+
+func TestLocationAltRep(t *testing.T) {
+	e := NewEvent("test-location")
+	_, err := e.GetLocationAltRep()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	e.SetLocation("Conference Room 1")
+	altRep, err := e.GetLocationAltRep()
+	assert.NoError(t, err)
+	assert.Equal(t, "", altRep)
+
+	e.SetLocationWithAltRep("Conference Room 1", "geo:37.386013,-122.082932")
+	altRep, err = e.GetLocationAltRep()
+	assert.NoError(t, err)
+	assert.Equal(t, "geo:37.386013,-122.082932", altRep)
+	assert.Contains(t, e.Serialize(), "LOCATION;ALTREP=\"geo:37.386013,-122.082932\":Conference Room 1\r\n")
+
+	e.SetLocation("Salle 1", WithLanguage("fr"))
+	assert.Contains(t, e.Serialize(), "LOCATION;LANGUAGE=fr:Salle 1\r\n")
+}
+
+func TestGeo(t *testing.T) {
+	e := NewEvent("test-geo")
+	assert.False(t, e.HasGeo())
+	_, _, err := e.GetGeo()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+
+	e.SetGeo(51.5, -0.1234567)
+	assert.True(t, e.HasGeo())
+	assert.Contains(t, e.Serialize(), "GEO:51.500000;-0.123457\r\n")
+	lat, lng, err := e.GetGeo()
+	assert.NoError(t, err)
+	assert.Equal(t, 51.5, lat)
+	assert.Equal(t, -0.123457, lng)
+
+	e.SetGeo("37.386013", "-122.082932")
+	assert.Contains(t, e.Serialize(), "GEO:37.386013;-122.082932\r\n")
+	e.SetProperty(ComponentPropertyGeo, "north;west")
+	_, _, err = e.GetGeo()
+	assert.Error(t, err)
+}
+
+func TestPriority(t *testing.T) {
+	e := NewEvent("test-priority")
+	_, err := e.GetPriority()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	assert.NoError(t, e.SetPriorityLevel(PriorityHigh))
+	assert.Error(t, e.SetPriorityLevel(10))
+	assert.Error(t, e.SetPriorityLevel(-1))
+	p, err := e.GetPriority()
+	assert.NoError(t, err)
+	assert.Equal(t, PriorityHigh, p)
+
+	todo := NewCalendar().AddTodo("test-todo-priority")
+	todo.SetPriority(3)
+	assert.Contains(t, todo.Serialize(), "PRIORITY:3\r\n")
+	todo.SetPriority(12)
+	_, err = todo.GetPriority()
+	assert.Error(t, err)
+}
+
+func TestTimeTransparency(t *testing.T) {
+	e := NewEvent("test-transp")
+	v, err := e.GetTimeTransparency()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	assert.Equal(t, TransparencyOpaque, v)
+	e.SetTimeTransparency(TransparencyTransparent)
+	v, err = e.GetTimeTransparency()
+	assert.NoError(t, err)
+	assert.Equal(t, TransparencyTransparent, v)
+	e.SetProperty(ComponentPropertyTransp, "TRANSPARANT")
+	_, err = e.GetTimeTransparency()
+	assert.Error(t, err)
+}
+
+func TestURL(t *testing.T) {
+	e := NewEvent("test-url")
+	_, err := e.GetURL()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	assert.Error(t, e.SetURL("/relative/path"))
+	assert.Error(t, e.SetURL("http://[::1"))
+	assert.NoError(t, e.SetURL("https://meet.example.com/abc?pwd=1"))
+	s, err := e.GetURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://meet.example.com/abc?pwd=1", s)
+	u, err := e.GetURLParsed()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "meet.example.com", u.Host)
+		assert.Equal(t, "1", u.Query().Get("pwd"))
+	}
+}
+
+func TestComments(t *testing.T) {
+	e := NewEvent("test-comments")
+	assert.Empty(t, e.GetComments())
+	e.AddComment("Bring slides")
+	e.AddComment("Room changed; see email")
+	assert.Contains(t, e.Serialize(), "COMMENT:Bring slides\r\nCOMMENT:Room changed\\; see email\r\n")
+	assert.Equal(t, []string{"Bring slides", "Room changed; see email"}, e.GetComments())
+	e.ClearComments()
+	assert.Empty(t, e.GetComments())
+}
+
+func TestRelatedTo(t *testing.T) {
+	todo := NewCalendar().AddTodo("subtask")
+	assert.Empty(t, todo.GetRelatedTo())
+	todo.AddRelatedTo("project", "")
+	todo.AddRelatedTo("other-subtask", RelationshipTypeSibling)
+	assert.Contains(t, todo.Serialize(), "RELATED-TO:project\r\nRELATED-TO;RELTYPE=SIBLING:other-subtask\r\n")
+	assert.Equal(t, []RelatedEntry{
+		{UID: "project", RelType: RelationshipTypeParent},
+		{UID: "other-subtask", RelType: RelationshipTypeSibling},
+	}, todo.GetRelatedTo())
+}
+
+func TestRelatedToRecurrenceException(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VEVENT
+UID:series
+DTSTART:20240101T090000Z
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:series-moved
+RECURRENCE-ID:20240102T090000Z
+DTSTART:20240102T100000Z
+RELATED-TO;RELTYPE=PARENT:series
+RELATED-TO;reltype=sibling:series-cancelled
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exception, ok := cal.FindEventByUID("series-moved")
+	if !assert.True(t, ok) {
+		return
+	}
+	related := exception.GetRelatedTo()
+	assert.Equal(t, []RelatedEntry{
+		{UID: "series", RelType: RelationshipTypeParent},
+		{UID: "series-cancelled", RelType: RelationshipTypeSibling},
+	}, related)
+	master, ok := cal.FindEventByUID(related[0].UID)
+	if assert.True(t, ok) {
+		assert.Equal(t, "series", master.Id())
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	e := NewEvent("test-attach")
+	attachments, err := e.GetAttachments()
+	assert.NoError(t, err)
+	assert.Empty(t, attachments)
+
+	e.AddAttachmentURL("https://example.com/agenda.pdf", "application/pdf")
+	e.AddAttachmentBinary([]byte("hello"), "text/plain")
+	assert.Contains(t, e.Serialize(), ":aGVsbG8=\r\n")
+	attachments, err = e.GetAttachments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Attachment{
+		{URI: "https://example.com/agenda.pdf", MimeType: "application/pdf"},
+		{IsInline: true, MimeType: "text/plain", Data: []byte("hello")},
+	}, attachments)
+	p := e.Properties[len(e.Properties)-1]
+	assert.Equal(t, []string{"BASE64"}, p.ICalParameters[string(ParameterEncoding)])
+	assert.Equal(t, []string{"BINARY"}, p.ICalParameters[string(ParameterValue)])
+
+	e.AddAttachment("not base64!", WithEncoding("BASE64"), WithValue(string(ValueDataTypeBinary)))
+	_, err = e.GetAttachments()
+	assert.Error(t, err)
+}
+
+func TestImages(t *testing.T) {
+	e := NewEvent("test-image")
+	assert.Empty(t, e.GetImages())
+
+	e.AddImage("https://example.com/badge.png", "image/png", ImageDisplayBadge)
+	e.AddImage("https://example.com/poster.jpg", "image/jpeg", ImageDisplayFullsize, ImageDisplayThumbnail)
+	e.AddImage("https://example.com/other.gif", "", "")
+	e.AddProperty(ComponentPropertyImage, "aGVsbG8=", WithValue(string(ValueDataTypeBinary)), WithEncoding("BASE64"))
+	assert.Contains(t, e.Serialize(), "IMAGE;VALUE=URI:https://example.com/other.gif\r\n")
+	assert.Contains(t, e.Serialize(), "IMAGE;DISPLAY=FULLSIZE,THUMBNAIL;FMTTYPE=image/jpeg;VALUE=URI:")
+
+	parsed, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\r\n" + e.Serialize() + "END:VCALENDAR\r\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Image{
+		{URI: "https://example.com/badge.png", MimeType: "image/png", Display: []ImageDisplay{ImageDisplayBadge}},
+		{URI: "https://example.com/poster.jpg", MimeType: "image/jpeg",
+			Display: []ImageDisplay{ImageDisplayFullsize, ImageDisplayThumbnail}},
+		{URI: "https://example.com/other.gif", Display: []ImageDisplay{ImageDisplayBadge}},
+	}, parsed.Events()[0].GetImages())
+}
+
+func TestConferences(t *testing.T) {
+	e := NewEvent("test-conference")
+	assert.Empty(t, e.GetConferences())
+
+	e.AddConference("https://chat.example.com/audio?id=123456",
+		WithFeature(ConferenceFeatureAudio, ConferenceFeatureVideo), WithLabel("Attendee dial-in"), WithLanguage("en"))
+	e.AddConference("tel:+1-412-555-0123,,,654321", WithFeature(ConferenceFeaturePhone, ConferenceFeatureModerator))
+	assert.Contains(t, e.Serialize(), "CONFERENCE;FEATURE=PHONE,MODERATOR;VALUE=URI:tel:+1-412-555-0123,,,654321\r\n")
+
+	parsed, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\r\n" + e.Serialize() + "END:VCALENDAR\r\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Conference{
+		{
+			URI:      "https://chat.example.com/audio?id=123456",
+			Features: []ConferenceFeature{ConferenceFeatureAudio, ConferenceFeatureVideo},
+			Label:    "Attendee dial-in",
+			Language: "en",
+		},
+		{
+			URI:      "tel:+1-412-555-0123,,,654321",
+			Features: []ConferenceFeature{ConferenceFeaturePhone, ConferenceFeatureModerator},
+		},
+	}, parsed.Events()[0].GetConferences())
+}
+
+func TestStructuredLocation(t *testing.T) {
+	e := NewEvent("test-structured-location")
+	_, err := e.GetStructuredLocation()
+	assert.ErrorIs(t, err, ErrPropertyNotFound)
+
+	want := StructuredLocation{
+		URI:        "geo:40.748817,-73.985428",
+		Label:      "Empire State Building",
+		Street:     "350 Fifth Avenue, Floor 86",
+		City:       "New York",
+		Region:     "NY",
+		PostalCode: "10118",
+		Country:    "US",
+	}
+	e.SetStructuredLocation(want)
+	p := e.GetProperty(ComponentPropertyStructuredLocation)
+	assert.Equal(t, []string{"350 Fifth Avenue, Floor 86"}, p.ICalParameters[string(ParameterStreet)])
+
+	parsed, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\r\n" + e.Serialize() + "END:VCALENDAR\r\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	l, err := parsed.Events()[0].GetStructuredLocation()
+	if assert.NoError(t, err) {
+		assert.Equal(t, want, *l)
+	}
+}
+
+func TestTextGetters(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nUID:1\r\n" +
+		"SUMMARY:Planning\\, part 2\r\n" +
+		"DESCRIPTION:Agenda:\\nReview\\; plan\\NWrap up\\\\done\r\n" +
+		"LOCATION:Room 1\\, Building A\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	e := cal.Events()[0]
+	summary, err := e.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "Planning, part 2", summary)
+	description, err := e.GetDescription()
+	assert.NoError(t, err)
+	assert.Equal(t, "Agenda:\nReview; plan\nWrap up\\done", description)
+	location, err := e.GetLocation()
+	assert.NoError(t, err)
+	assert.Equal(t, "Room 1, Building A", location)
+
+	_, err = NewEvent("empty").GetSummary()
+	assert.Equal(t, ErrPropertyNotFound, err)
+}
+
+func TestSummaryLanguage(t *testing.T) {
+	e := NewEvent("test-language")
+	_, err := e.GetSummaryLanguage()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+	e.SetSummary("Planning")
+	lang, err := e.GetSummaryLanguage()
+	assert.NoError(t, err)
+	assert.Equal(t, "", lang)
+	e.SetSummaryWithLanguage("Planung", "de")
+	assert.Contains(t, e.Serialize(), "SUMMARY;LANGUAGE=de:Planung\r\n")
+	lang, err = e.GetSummaryLanguage()
+	assert.NoError(t, err)
+	assert.Equal(t, "de", lang)
+	summary, err := e.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "Planung", summary)
+}
+
+func TestContacts(t *testing.T) {
+	e := NewEvent("test-contacts")
+	assert.Empty(t, e.GetContacts())
+	e.AddContact("Jim Dolittle, ABC Industries, +1-919-555-1234")
+	e.AddContactWithAltRep("Joe Bloggs", "http://example.com/pdi/jdoe.vcf")
+	assert.Contains(t, e.Serialize(), "CONTACT:Jim Dolittle\\, ABC Industries\\, +1-919-555-1234\r\n")
+	assert.Contains(t, e.Serialize(), "CONTACT;ALTREP=\"http://example.com/pdi/jdoe.vcf\":Joe Bloggs\r\n")
+	assert.Equal(t, []string{"Jim Dolittle, ABC Industries, +1-919-555-1234", "Joe Bloggs"}, e.GetContacts())
+}
+
+func TestSetAllDay(t *testing.T) {
+	e := NewEvent("all-day")
+	assert.False(t, e.IsAllDay())
+	e.SetStartAt(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	e.SetEndAt(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	assert.False(t, e.IsAllDay())
+
+	e.SetAllDay(time.Date(2024, 5, 2, 0, 0, 0, 0, time.FixedZone("", 10*60*60)))
+	assert.True(t, e.IsAllDay())
+	s := e.Serialize()
+	assert.Contains(t, s, "DTSTART;VALUE=DATE:20240502\r\n")
+	assert.Contains(t, s, "DURATION:P1D\r\n")
+	assert.NotContains(t, s, "DTEND")
+
+	e.SetProperty(ComponentPropertyDtStart, "20240502")
+	assert.True(t, e.IsAllDay())
+}
+
+func TestAttendeePartStat(t *testing.T) {
+	e := NewEvent("partstat")
+	e.AddAttendee("Alice@Example.com")
+	e.AddAttendee("bob@example.com", WithPartStat(ParticipationStatusTentative))
+
+	status, err := e.GetAttendeePartStat("alice@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, ParticipationStatusNeedsAction, status)
+	status, err = e.GetAttendeePartStat("mailto:bob@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, ParticipationStatusTentative, status)
+
+	assert.NoError(t, e.SetAttendeePartStat("alice@example.com", ParticipationStatusAccepted))
+	status, err = e.GetAttendeePartStat("alice@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, ParticipationStatusAccepted, status)
+	assert.Contains(t, e.Serialize(), "ATTENDEE;PARTSTAT=ACCEPTED:mailto:Alice@Example.com\r\n")
+
+	err = e.SetAttendeePartStat("carol@example.com", ParticipationStatusDeclined)
+	assert.True(t, errors.Is(err, ErrAttendeeNotFound))
+	_, err = e.GetAttendeePartStat("carol@example.com")
+	assert.True(t, errors.Is(err, ErrAttendeeNotFound))
+}
+
+func TestOverlapsWith(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 4, day, hour, 0, 0, 0, time.UTC)
+	}
+	timed := func(start, end time.Time) *VEvent {
+		e := NewEvent("timed")
+		e.SetStartAt(start)
+		e.SetEndAt(end)
+		return e
+	}
+	meeting := timed(at(10, 9), at(10, 10))
+	withDuration := NewEvent("duration")
+	withDuration.SetStartAt(at(10, 8))
+	assert.NoError(t, withDuration.SetDuration(90*time.Minute))
+	instant := NewEvent("instant")
+	instant.SetStartAt(at(10, 10))
+	allDay := NewEvent("all-day")
+	allDay.SetProperty(ComponentPropertyDtStart, "20240410", WithValue(string(ValueDataTypeDate)))
+	allDay.SetProperty(ComponentPropertyDtEnd, "20240411", WithValue(string(ValueDataTypeDate)))
+	local := func(day int, hour int) time.Time {
+		return time.Date(2024, 4, day, hour, 0, 0, 0, time.Local)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		a, b     *VEvent
+		expected bool
+	}{
+		{"same", meeting, meeting, true},
+		{"duration overlapping", meeting, withDuration, true},
+		{"abutting", meeting, timed(at(10, 10), at(10, 11)), false},
+		{"instant at end", meeting, instant, false},
+		{"instant at start", instant, timed(at(10, 10), at(10, 11)), true},
+		{"all-day", allDay, timed(local(10, 12), local(10, 13)), true},
+		{"day after all-day", allDay, timed(local(11, 0), local(11, 1)), false},
+	} {
+		got, err := tc.a.OverlapsWith(tc.b)
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expected, got, tc.name)
+		got, err = tc.b.OverlapsWith(tc.a)
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expected, got, tc.name)
+	}
+
+	_, err := NewEvent("no-start").OverlapsWith(meeting)
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+}
@@ -0,0 +1,542 @@
+package ics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jCalTypeUnknown is the jCal type of properties whose value type is not known, see RFC 7265 §5.
+const jCalTypeUnknown = "unknown"
+
+var (
+	// listProperties hold comma separated lists of values, each of which becomes its own jCal value.
+	listProperties = map[Property]bool{
+		PropertyCategories: true,
+		PropertyResources:  true,
+		PropertyExdate:     true,
+		PropertyRdate:      true,
+		PropertyFreebusy:   true,
+	}
+	// structuredProperties hold semicolon separated fields, which become a single array valued jCal value.
+	structuredProperties = map[Property]bool{
+		PropertyGeo:           true,
+		PropertyRequestStatus: true,
+	}
+	// recurRuleParts is the order rule parts are written in. Unknown parts follow in alphabetical order.
+	recurRuleParts = []string{"FREQ", "UNTIL", "COUNT", "INTERVAL", "BYSECOND", "BYMINUTE", "BYHOUR", "BYDAY", "BYMONTHDAY",
+		"BYYEARDAY", "BYWEEKNO", "BYMONTH", "BYSETPOS", "WKST"}
+)
+
+// jsonObject is a JSON object which keeps the order of its members.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString("{")
+	for i, m := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// MarshalJSON encodes the calendar as jCal, the JSON representation of iCalendar defined in RFC 7265.
+func (calendar *Calendar) MarshalJSON() ([]byte, error) {
+	props := make([]interface{}, 0, len(calendar.CalendarProperties))
+	for i := range calendar.CalendarProperties {
+		props = append(props, propertyToJCal(&calendar.CalendarProperties[i].BaseProperty))
+	}
+	components, err := componentsToJCal(calendar.Components)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal([]interface{}{"vcalendar", props, components})
+}
+
+// UnmarshalJSON decodes a jCal calendar, replacing the properties and components of the calendar.
+func (calendar *Calendar) UnmarshalJSON(b []byte) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	name, cb, err := jCalToComponentBase(v)
+	if err != nil {
+		return err
+	}
+	if name != string(ComponentVCalendar) {
+		return fmt.Errorf("malformed jcal; expected a vcalendar, got %s", name)
+	}
+	calendar.CalendarProperties = make([]CalendarProperty, 0, len(cb.Properties))
+	for _, p := range cb.Properties {
+		calendar.CalendarProperties = append(calendar.CalendarProperties, CalendarProperty{p.BaseProperty})
+	}
+	calendar.Components = cb.Components
+	if calendar.Components == nil {
+		calendar.Components = []Component{}
+	}
+	for _, c := range calendar.Components {
+		c.setCalendar(calendar)
+	}
+	return nil
+}
+
+func componentsToJCal(components []Component) ([]interface{}, error) {
+	r := make([]interface{}, 0, len(components))
+	for _, c := range components {
+		name := componentName(c)
+		if name == "" {
+			return nil, fmt.Errorf("unsupported component type %T", c)
+		}
+		properties := c.UnknownPropertiesIANAProperties()
+		props := make([]interface{}, 0, len(properties))
+		for i := range properties {
+			props = append(props, propertyToJCal(&properties[i].BaseProperty))
+		}
+		sub, err := componentsToJCal(c.SubComponents())
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, []interface{}{strings.ToLower(name), props, sub})
+	}
+	return r, nil
+}
+
+func propertyToJCal(p *BaseProperty) []interface{} {
+	params := jsonObject{}
+	for _, k := range sortedParameterKeys(p.ICalParameters) {
+		vs := p.ICalParameters[k]
+		var v interface{} = vs
+		if len(vs) == 1 {
+			v = vs[0]
+		}
+		params = append(params, jsonMember{strings.ToLower(k), v})
+	}
+	t := p.representedValueDataType()
+	values, err := icalToJCalValues(Property(strings.ToUpper(p.IANAToken)), t, p.Value)
+	if err != nil {
+		// Values which don't match their type are passed on verbatim
+		t, values = jCalTypeUnknown, []interface{}{p.Value}
+	}
+	return append([]interface{}{strings.ToLower(p.IANAToken), params, strings.ToLower(string(t))}, values...)
+}
+
+// representedValueDataType is the value type jCal and xCal use for the property, "unknown" if it can't be told.
+func (property *BaseProperty) representedValueDataType() ValueDataType {
+	if _, known := defaultValueDataType(property.IANAToken); known || len(property.ICalParameters[string(ParameterValue)]) > 0 {
+		return property.valueDataType()
+	}
+	return jCalTypeUnknown
+}
+
+// setRepresentedValueDataType adds a VALUE parameter if t isn't the default value type of the property.
+func (property *BaseProperty) setRepresentedValueDataType(t ValueDataType) {
+	if dt, _ := defaultValueDataType(property.IANAToken); !strings.EqualFold(string(t), jCalTypeUnknown) && t != dt {
+		property.ICalParameters[string(ParameterValue)] = []string{string(t)}
+	}
+}
+
+// sortedParameterKeys returns the parameter names other than VALUE, which jCal and xCal carry as the value type.
+func sortedParameterKeys(params map[string][]string) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if !strings.EqualFold(k, string(ParameterValue)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func icalToJCalValues(property Property, t ValueDataType, value string) ([]interface{}, error) {
+	if t == jCalTypeUnknown {
+		return []interface{}{value}, nil
+	}
+	if structuredProperties[property] {
+		var fields []interface{}
+		for _, f := range splitUnescaped(value, ';') {
+			v, err := icalToJCalValue(t, f)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, v)
+		}
+		return []interface{}{fields}, nil
+	}
+	raw := []string{value}
+	if listProperties[property] {
+		raw = splitUnescaped(value, ',')
+	}
+	r := make([]interface{}, 0, len(raw))
+	for _, s := range raw {
+		v, err := icalToJCalValue(t, s)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
+
+func icalToJCalValue(t ValueDataType, s string) (interface{}, error) {
+	switch t {
+	case ValueDataTypeText:
+		return FromText(s), nil
+	case ValueDataTypeDate, ValueDataTypeDateTime, ValueDataTypeTime:
+		return icalToISOTime(t, s)
+	case ValueDataTypePeriod:
+		return icalToISOPeriod(s)
+	case ValueDataTypeUtcOffset:
+		return icalToISOUtcOffset(s)
+	case ValueDataTypeInteger:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		return i, nil
+	case ValueDataTypeFloat:
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, err
+		}
+		return json.Number(s), nil
+	case ValueDataTypeBoolean:
+		return strconv.ParseBool(s)
+	case ValueDataTypeRecur:
+		return recurToJCal(s)
+	}
+	return s, nil
+}
+
+// icalToISOTime converts DATE, DATE-TIME and TIME values to the ISO 8601 extended format used by jCal and xCal.
+func icalToISOTime(t ValueDataType, s string) (string, error) {
+	utc := strings.HasSuffix(s, "Z")
+	v := strings.TrimSuffix(s, "Z")
+	if !isDigits(strings.Replace(v, "T", "", 1)) {
+		return "", fmt.Errorf("malformed %s value '%s'", t, s)
+	}
+	var r string
+	switch {
+	case t == ValueDataTypeDate && len(v) == 8 && !utc:
+		return v[0:4] + "-" + v[4:6] + "-" + v[6:8], nil
+	case t == ValueDataTypeDateTime && len(v) == 15 && v[8] == 'T':
+		r = v[0:4] + "-" + v[4:6] + "-" + v[6:8] + "T" + v[9:11] + ":" + v[11:13] + ":" + v[13:15]
+	case t == ValueDataTypeTime && len(v) == 6:
+		r = v[0:2] + ":" + v[2:4] + ":" + v[4:6]
+	default:
+		return "", fmt.Errorf("malformed %s value '%s'", t, s)
+	}
+	if utc {
+		r += "Z"
+	}
+	return r, nil
+}
+
+func isoToICalTime(s string) string {
+	return strings.NewReplacer("-", "", ":", "").Replace(s)
+}
+
+func icalToISOPeriod(s string) (string, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed period '%s'", s)
+	}
+	start, err := icalToISOTime(ValueDataTypeDateTime, parts[0])
+	if err != nil {
+		return "", err
+	}
+	end := parts[1]
+	if !strings.ContainsAny(end, "P") {
+		end, err = icalToISOTime(ValueDataTypeDateTime, end)
+		if err != nil {
+			return "", err
+		}
+	}
+	return start + "/" + end, nil
+}
+
+func isoToICalPeriod(s string) string {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 && !strings.ContainsAny(parts[1], "P") {
+		return isoToICalTime(parts[0]) + "/" + isoToICalTime(parts[1])
+	}
+	return isoToICalTime(parts[0]) + "/" + strings.Join(parts[1:], "/")
+}
+
+func icalToISOUtcOffset(s string) (string, error) {
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') || !isDigits(s[1:]) {
+		return "", fmt.Errorf("malformed utc offset '%s'", s)
+	}
+	r := s[0:3] + ":" + s[3:5]
+	if len(s) == 7 {
+		r += ":" + s[5:7]
+	}
+	return r, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// splitRecur splits an RRULE value into its parts in the order they should be written.
+func splitRecur(s string) ([]jsonMember, error) {
+	parts := map[string]string{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("malformed recurrence rule part '%s'", part)
+		}
+		parts[strings.ToUpper(kv[0])] = kv[1]
+	}
+	return orderRecurParts(parts), nil
+}
+
+func orderRecurParts(parts map[string]string) []jsonMember {
+	var r []jsonMember
+	for _, k := range recurRuleParts {
+		if v, ok := parts[k]; ok {
+			r = append(r, jsonMember{k, v})
+			delete(parts, k)
+		}
+	}
+	var rest []string
+	for k := range parts {
+		rest = append(rest, k)
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		r = append(r, jsonMember{k, parts[k]})
+	}
+	return r
+}
+
+func recurToJCal(s string) (jsonObject, error) {
+	parts, err := splitRecur(s)
+	if err != nil {
+		return nil, err
+	}
+	r := jsonObject{}
+	for _, part := range parts {
+		v := part.Value.(string)
+		var values []interface{}
+		for _, rv := range strings.Split(v, ",") {
+			switch part.Key {
+			case "UNTIL":
+				t := ValueDataTypeDateTime
+				if len(rv) == 8 {
+					t = ValueDataTypeDate
+				}
+				iso, err := icalToISOTime(t, rv)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, iso)
+			case "FREQ", "WKST", "BYDAY":
+				values = append(values, rv)
+			default:
+				i, err := strconv.Atoi(rv)
+				if err != nil {
+					values = append(values, rv)
+				} else {
+					values = append(values, i)
+				}
+			}
+		}
+		if len(values) == 1 {
+			r = append(r, jsonMember{strings.ToLower(part.Key), values[0]})
+		} else {
+			r = append(r, jsonMember{strings.ToLower(part.Key), values})
+		}
+	}
+	return r, nil
+}
+
+func jCalToComponentBase(v interface{}) (string, ComponentBase, error) {
+	cb := ComponentBase{}
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 3 {
+		return "", cb, errors.New("malformed jcal; expected a component array of 3 elements")
+	}
+	name, ok := a[0].(string)
+	if !ok {
+		return "", cb, errors.New("malformed jcal; expected a component name")
+	}
+	props, ok := a[1].([]interface{})
+	if !ok {
+		return "", cb, fmt.Errorf("malformed jcal; expected the properties of %s", name)
+	}
+	for _, pv := range props {
+		p, err := jCalToProperty(pv)
+		if err != nil {
+			return "", cb, err
+		}
+		cb.Properties = append(cb.Properties, IANAProperty{*p})
+	}
+	components, ok := a[2].([]interface{})
+	if !ok {
+		return "", cb, fmt.Errorf("malformed jcal; expected the components of %s", name)
+	}
+	for _, cv := range components {
+		subName, sub, err := jCalToComponentBase(cv)
+		if err != nil {
+			return "", cb, err
+		}
+		cb.Components = append(cb.Components, newComponent(subName, sub))
+	}
+	return strings.ToUpper(name), cb, nil
+}
+
+func jCalToProperty(v interface{}) (*BaseProperty, error) {
+	a, ok := v.([]interface{})
+	if !ok || len(a) < 4 {
+		return nil, errors.New("malformed jcal; expected a property array of at least 4 elements")
+	}
+	name, ok := a[0].(string)
+	if !ok {
+		return nil, errors.New("malformed jcal; expected a property name")
+	}
+	r := &BaseProperty{
+		IANAToken:      strings.ToUpper(name),
+		ICalParameters: map[string][]string{},
+	}
+	params, ok := a[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("malformed jcal; expected the parameters of %s", name)
+	}
+	for k, pv := range params {
+		var vs []string
+		switch pv := pv.(type) {
+		case []interface{}:
+			for _, e := range pv {
+				vs = append(vs, jsonScalarToString(e))
+			}
+		default:
+			vs = []string{jsonScalarToString(pv)}
+		}
+		r.ICalParameters[strings.ToUpper(k)] = vs
+	}
+	jt, ok := a[2].(string)
+	if !ok {
+		return nil, fmt.Errorf("malformed jcal; expected the value type of %s", name)
+	}
+	t := ValueDataType(strings.ToUpper(jt))
+	values := make([]string, 0, len(a)-3)
+	for _, jv := range a[3:] {
+		s, err := jCalToICalValue(t, jv)
+		if err != nil {
+			return nil, fmt.Errorf("malformed jcal; value of %s: %w", name, err)
+		}
+		values = append(values, s)
+	}
+	r.Value = strings.Join(values, ",")
+	r.setRepresentedValueDataType(t)
+	return r, nil
+}
+
+func jsonScalarToString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func jCalToICalValue(t ValueDataType, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		// Structured values
+		fields := make([]string, 0, len(v))
+		for _, f := range v {
+			s, err := jCalToICalValue(t, f)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, s)
+		}
+		return strings.Join(fields, ";"), nil
+	case map[string]interface{}:
+		if t != ValueDataTypeRecur {
+			return "", fmt.Errorf("unexpected object for %s", t)
+		}
+		return jCalToRecur(v), nil
+	case string:
+		switch t {
+		case ValueDataTypeText:
+			return ToText(v), nil
+		case ValueDataTypeDate, ValueDataTypeDateTime, ValueDataTypeTime:
+			return isoToICalTime(v), nil
+		case ValueDataTypeUtcOffset:
+			return strings.Replace(v, ":", "", -1), nil
+		case ValueDataTypePeriod:
+			return isoToICalPeriod(v), nil
+		}
+		return v, nil
+	}
+	return jsonScalarToString(v), nil
+}
+
+func jCalToRecur(o map[string]interface{}) string {
+	parts := map[string]string{}
+	for k, v := range o {
+		k = strings.ToUpper(k)
+		var vs []string
+		switch v := v.(type) {
+		case []interface{}:
+			for _, e := range v {
+				vs = append(vs, jsonScalarToString(e))
+			}
+		default:
+			vs = []string{jsonScalarToString(v)}
+		}
+		if k == "UNTIL" {
+			for i := range vs {
+				vs[i] = isoToICalTime(vs[i])
+			}
+		}
+		parts[k] = strings.Join(vs, ",")
+	}
+	return joinRecur(parts)
+}
+
+func joinRecur(parts map[string]string) string {
+	var r []string
+	for _, part := range orderRecurParts(parts) {
+		r = append(r, part.Key+"="+part.Value.(string))
+	}
+	return strings.Join(r, ";")
+}
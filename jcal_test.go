@@ -0,0 +1,110 @@
+package ics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJCalRoundTrip(t *testing.T) {
+	err := filepath.Walk("./testdata/rfc5545sec4/", func(path string, info os.FileInfo, _ error) error {
+		if info.IsDir() {
+			return nil
+		}
+		inputBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cal, err := ParseCalendar(strings.NewReader(string(inputBytes)))
+		if !assert.NoError(t, err, path) {
+			return nil
+		}
+		b, err := json.Marshal(cal)
+		if !assert.NoError(t, err, path) {
+			return nil
+		}
+		parsed := &Calendar{}
+		if assert.NoError(t, json.Unmarshal(b, parsed), path) {
+			// Parameter order isn't stable in the iCalendar output, jCal parameters are sorted
+			again, err := json.Marshal(parsed)
+			assert.NoError(t, err, path)
+			assert.Equal(t, string(b), string(again), path)
+			assert.Equal(t, calendarContents(cal), calendarContents(parsed), path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot read test directory: %v", err)
+	}
+}
+
+func TestJCalMarshal(t *testing.T) {
+	cal := NewCalendarFor("test")
+	event := cal.AddEvent("123")
+	event.SetStartAt(time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC))
+	event.SetSummary("Planning, meeting", WithCN("Bob"))
+	event.AddRrule("FREQ=WEEKLY;COUNT=2;BYDAY=TU,TH")
+	event.SetGeo(37.386013, -122.082932)
+	event.SetProperty(ComponentPropertyPriority, "5")
+	todo := cal.AddTodo("456")
+	todo.SetAllDayDueAt(time.Date(1997, 9, 3, 0, 0, 0, 0, time.UTC))
+
+	b, err := json.Marshal(cal)
+	assert.NoError(t, err)
+	expected := `["vcalendar",[["version",{},"text","2.0"],["prodid",{},"text","-//test//Golang ICS Library"]],` +
+		`[["vevent",[["uid",{},"text","123"],["dtstart",{},"date-time","1997-09-02T09:00:00Z"],` +
+		`["summary",{"cn":"Bob"},"text","Planning, meeting"],` +
+		`["rrule",{},"recur",{"freq":"WEEKLY","count":2,"byday":["TU","TH"]}],` +
+		`["geo",{},"float",[37.386013,-122.082932]],["priority",{},"integer",5]],[]],` +
+		`["vtodo",[["uid",{},"text","456"],["due",{},"date","1997-09-03"]],[]]]]`
+	assert.Equal(t, expected, string(b))
+
+	parsed := &Calendar{}
+	if assert.NoError(t, json.Unmarshal(b, parsed)) {
+		assert.Equal(t, cal.Serialize(), parsed.Serialize())
+	}
+}
+
+func TestJCalUnmarshalErrors(t *testing.T) {
+	for _, s := range []string{`{}`, `["vevent",[],[]]`, `["vcalendar",[["version"]],[]]`, `["vcalendar",[],[["vevent",{},[]]]]`} {
+		assert.Error(t, json.Unmarshal([]byte(s), &Calendar{}), s)
+	}
+}
+
+// calendarContents lists the properties of the calendar and its components, with their parameters and values, one per
+// line in order, so calendars can be compared regardless of parameter order.
+func calendarContents(cal *Calendar) []string {
+	var r []string
+	for _, p := range cal.CalendarProperties {
+		r = append(r, propertyContents("VCALENDAR", p.BaseProperty))
+	}
+	var walk func(prefix string, components []Component)
+	walk = func(prefix string, components []Component) {
+		for _, c := range components {
+			name := prefix + "/" + strings.ToUpper(componentName(c))
+			r = append(r, name)
+			for _, p := range c.UnknownPropertiesIANAProperties() {
+				r = append(r, propertyContents(name, p.BaseProperty))
+			}
+			walk(name, c.SubComponents())
+		}
+	}
+	walk("VCALENDAR", cal.Components)
+	return r
+}
+
+func propertyContents(component string, p BaseProperty) string {
+	params := make([]string, 0, len(p.ICalParameters))
+	for k, vs := range p.ICalParameters {
+		params = append(params, k+"="+strings.Join(vs, ","))
+	}
+	sort.Strings(params)
+	return component + " " + p.IANAToken + ";" + strings.Join(params, ";") + ":" + p.Value
+}
@@ -2,12 +2,18 @@ package ics
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"mime/quotedprintable"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -65,6 +71,68 @@ func WithRSVP(b bool) PropertyParameter {
 	}
 }
 
+// WithAltRep gives the URI of an alternate representation of a TEXT value.
+func WithAltRep(uri string) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterAltrep),
+		Value: []string{uri},
+	}
+}
+
+// WithLanguage gives the language of a TEXT value, as a language tag such as "en-US".
+func WithLanguage(tag string) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterLanguage),
+		Value: []string{tag},
+	}
+}
+
+// WithFeature lists the features of a CONFERENCE.
+func WithFeature(features ...ConferenceFeature) PropertyParameter {
+	kv := &KeyValues{Key: string(ParameterFeature)}
+	for _, f := range features {
+		kv.Value = append(kv.Value, string(f))
+	}
+	return kv
+}
+
+// WithLabel gives the text shown to users for a CONFERENCE.
+func WithLabel(label string) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterLabel),
+		Value: []string{label},
+	}
+}
+
+// WithSentBy names the email address of the calendar user acting on behalf of the one given by the property.
+func WithSentBy(email string) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterSentBy),
+		Value: []string{mailtoURI(email)},
+	}
+}
+
+func WithRole(role ParticipationRole) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterRole),
+		Value: []string{string(role)},
+	}
+}
+
+func WithPartStat(status ParticipationStatus) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterParticipationStatus),
+		Value: []string{string(status)},
+	}
+}
+
+func WithCUType(cuType CalendarUserType) PropertyParameter {
+	return &KeyValues{
+		Key:   string(ParameterCutype),
+		Value: []string{string(cuType)},
+	}
+}
+
 func trimUT8StringUpTo(maxLength int, s string) string {
 	length := 0
 	lastSpace := -1
@@ -87,45 +155,219 @@ func trimUT8StringUpTo(maxLength int, s string) string {
 }
 
 func (property *BaseProperty) serialize(w io.Writer) {
+	pw := propertyWriter{w: w, lineLength: defaultLineLength}
+	if ew, ok := w.(*errorWriter); ok && ew.lineLength != 0 {
+		pw.lineLength = ew.lineLength
+	}
+	_ = pw.writeProperty(property.IANAToken, property.ICalParameters, property.Value)
+}
+
+// transparentEncoding returns the ENCODING DecodedValue undoes, or an empty string if the value is used as written.
+// QUOTED-PRINTABLE, sent by some legacy servers, is always decoded. BASE64 is only decoded from values which aren't
+// meant to be binary, as GetAttachments decodes inline attachments.
+func (property *BaseProperty) transparentEncoding() string {
+	vs := property.ICalParameters[string(ParameterEncoding)]
+	if len(vs) == 0 {
+		return ""
+	}
+	switch encoding := strings.ToUpper(vs[0]); encoding {
+	case "QUOTED-PRINTABLE":
+		return encoding
+	case "BASE64":
+		if property.valueDataType() == ValueDataTypeBinary || property.IANAToken == string(PropertyAttach) {
+			return ""
+		}
+		return encoding
+	}
+	return ""
+}
+
+// DecodedValue returns the value with a QUOTED-PRINTABLE or, for values which aren't binary, BASE64 ENCODING undone.
+// The decoded bytes are converted to UTF-8 from the CHARSET of the property, which may be UTF-8, US-ASCII, ISO-8859-1
+// or windows-1252; other charsets are an error. Values without such an ENCODING are returned as they are. Value always
+// holds the value as written, so it is serialized unchanged; the text getters such as GetSummary use DecodedValue,
+// falling back to Value when it can't be decoded. An error is returned if the value isn't validly encoded, which
+// strict parsing reports.
+func (property *BaseProperty) DecodedValue() (string, error) {
+	var b []byte
+	var err error
+	switch encoding := property.transparentEncoding(); encoding {
+	case "QUOTED-PRINTABLE":
+		b, err = ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(property.Value)))
+		if err != nil {
+			return "", fmt.Errorf("decoding %s: %w", encoding, err)
+		}
+	case "BASE64":
+		b, err = base64.StdEncoding.DecodeString(property.Value)
+		if err != nil {
+			return "", fmt.Errorf("decoding %s: %w", encoding, err)
+		}
+	default:
+		return property.Value, nil
+	}
+	vs := property.ICalParameters[string(ParameterCharset)]
+	if len(vs) == 0 {
+		return string(b), nil
+	}
+	switch charset := strings.ToUpper(vs[0]); charset {
+	case "UTF-8", "UTF8", "US-ASCII", "ASCII":
+		return string(b), nil
+	case "ISO-8859-1", "ISO8859-1", "ISO_8859-1", "LATIN1":
+		return decodeSingleByte(b, nil), nil
+	case "WINDOWS-1252", "CP1252":
+		return decodeSingleByte(b, &windows1252), nil
+	default:
+		return "", fmt.Errorf("decoding %s: unsupported charset", charset)
+	}
+}
+
+// windows1252 holds the characters windows-1252 has in place of the C1 controls of ISO-8859-1, 0x80 to 0x9F. Bytes
+// windows-1252 leaves undefined keep their ISO-8859-1 meaning.
+var windows1252 = [32]rune{
+	'\u20AC', '\u0081', '\u201A', '\u0192', '\u201E', '\u2026', '\u2020', '\u2021',
+	'\u02C6', '\u2030', '\u0160', '\u2039', '\u0152', '\u008D', '\u017D', '\u008F',
+	'\u0090', '\u2018', '\u2019', '\u201C', '\u201D', '\u2022', '\u2013', '\u2014',
+	'\u02DC', '\u2122', '\u0161', '\u203A', '\u0153', '\u009D', '\u017E', '\u0178',
+}
+
+// decodeSingleByte converts text in ISO-8859-1 to UTF-8, looking up bytes 0x80 to 0x9F in c1 if given.
+func decodeSingleByte(b []byte, c1 *[32]rune) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+		if c1 != nil && c >= 0x80 && c <= 0x9F {
+			r[i] = c1[c-0x80]
+		}
+	}
+	return string(r)
+}
+
+// SetDecodedValue sets the value from its decoded form, encoding it as DecodedValue expects from the ENCODING of the
+// property. Without such an ENCODING the value is set as given. The value is encoded as UTF-8, so a CHARSET other than
+// UTF-8 is removed.
+func (property *BaseProperty) SetDecodedValue(s string) {
+	if vs := property.ICalParameters[string(ParameterCharset)]; len(vs) > 0 && property.transparentEncoding() != "" {
+		switch strings.ToUpper(vs[0]) {
+		case "UTF-8", "UTF8":
+		default:
+			delete(property.ICalParameters, string(ParameterCharset))
+		}
+	}
+	switch property.transparentEncoding() {
+	case "QUOTED-PRINTABLE":
+		b := &strings.Builder{}
+		w := quotedprintable.NewWriter(b)
+		// Line breaks in the value are encoded rather than written as is
+		w.Binary = true
+		_, _ = io.WriteString(w, s)
+		_ = w.Close()
+		// Soft line breaks would end the content line, which is folded instead
+		property.Value = strings.Replace(b.String(), "=\r\n", "", -1)
+	case "BASE64":
+		property.Value = base64.StdEncoding.EncodeToString([]byte(s))
+	default:
+		property.Value = s
+	}
+}
+
+// textValue returns the decoded value of a TEXT property, unescaped. A value which can't be decoded is used as written.
+func (property *BaseProperty) textValue() string {
+	v, err := property.DecodedValue()
+	if err != nil {
+		v = property.Value
+	}
+	return FromText(v)
+}
+
+// WriteProperty writes a single content line, for those building their own serializers. The parameters are written in
+// name order, quoted where their values need it, and the line is folded to at most 75 octets and ended with CRLF. The
+// value is written as given so should already be escaped, for example with ToText.
+func WriteProperty(w io.Writer, name string, params map[string][]string, value string) error {
+	return propertyWriter{w: w, lineLength: defaultLineLength}.writeProperty(name, params, value)
+}
+
+// defaultLineLength is the length in octets RFC 5545 section 3.1 limits content lines to.
+const defaultLineLength = 75
+
+// propertyWriter writes content lines honouring the line length and line ending rules of RFC 5545 section 3.1.
+type propertyWriter struct {
+	w io.Writer
+	// lineLength is the length in octets lines are folded to
+	lineLength int
+}
+
+func (pw propertyWriter) writeProperty(name string, params map[string][]string, value string) error {
 	b := bytes.NewBufferString("")
-	fmt.Fprint(b, property.IANAToken)
-	for k, vs := range property.ICalParameters {
+	fmt.Fprint(b, name)
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
 		fmt.Fprint(b, ";")
 		fmt.Fprint(b, k)
 		fmt.Fprint(b, "=")
-		for vi, v := range vs {
+		for vi, v := range params[k] {
 			if vi > 0 {
 				fmt.Fprint(b, ",")
 			}
 			if strings.ContainsAny(v, ";:\\\",") {
-				v = strings.Replace(v, "\"", "\\\"", -1)
+				// Backslashes first so the escapes added for quotes aren't escaped again
 				v = strings.Replace(v, "\\", "\\\\", -1)
+				v = strings.Replace(v, "\"", "\\\"", -1)
+			}
+			if strings.ContainsAny(v, ";:,") {
+				// Such values are only allowed as a quoted-string
+				v = "\"" + v + "\""
 			}
 			fmt.Fprint(b, v)
 		}
 	}
 	fmt.Fprint(b, ":")
-	fmt.Fprint(b, property.Value)
-	r := b.String()
-	if len(r) > 75 {
-		l := trimUT8StringUpTo(75, r)
-		fmt.Fprint(w, l, "\r\n")
+	// A line break would end the content line, so any left unescaped in the value are written as TEXT escapes
+	fmt.Fprint(b, lineBreakEscaper.Replace(value))
+	_, err := io.WriteString(pw.w, foldLine(b.String(), pw.lineLength))
+	return err
+}
+
+// foldLine splits a content line into lines of at most n octets, preferring to break before a space and never
+// splitting a UTF-8 sequence, and ends each with CRLF. Continuation lines start with a single space which unfolding
+// removes.
+func foldLine(r string, n int) string {
+	b := &strings.Builder{}
+	if len(r) > n {
+		l := trimUT8StringUpTo(n, r)
+		b.WriteString(l + "\r\n")
 		r = r[len(l):]
 
-		for len(r) > 74 {
-			l := trimUT8StringUpTo(74, r)
-			fmt.Fprint(w, " ", l, "\r\n")
+		for len(r) > n-1 {
+			l := trimUT8StringUpTo(n-1, r)
+			b.WriteString(" " + l + "\r\n")
 			r = r[len(l):]
 		}
-		fmt.Fprint(w, " ")
+		b.WriteString(" ")
 	}
-	fmt.Fprint(w, r, "\r\n")
+	b.WriteString(r + "\r\n")
+	return b.String()
 }
 
 type IANAProperty struct {
 	BaseProperty
 }
 
+// clone returns a deep copy of the property.
+func (property *BaseProperty) clone() BaseProperty {
+	r := *property
+	if property.ICalParameters != nil {
+		r.ICalParameters = make(map[string][]string, len(property.ICalParameters))
+		for k, v := range property.ICalParameters {
+			r.ICalParameters[k] = append([]string(nil), v...)
+		}
+	}
+	return r
+}
+
 var (
 	propertyIanaTokenReg *regexp.Regexp
 	propertyParamNameReg *regexp.Regexp
@@ -156,7 +398,8 @@ func ParseProperty(contentLine ContentLine) (*BaseProperty, error) {
 		return nil, nil
 	}
 	p := 0
-	r.IANAToken = string(contentLine[p+tokenPos[0] : p+tokenPos[1]])
+	// Names are case insensitive, so they're kept in the upper case of the Property constants
+	r.IANAToken = strings.ToUpper(string(contentLine[p+tokenPos[0] : p+tokenPos[1]]))
 	p += tokenPos[1]
 	for {
 		if p >= len(contentLine) {
@@ -189,7 +432,8 @@ func parsePropertyParam(r *BaseProperty, contentLine string, p int) (*BaseProper
 		return nil, p, nil
 	}
 	k, v := "", ""
-	k = string(contentLine[p : p+tokenPos[1]])
+	// Parameter names are case insensitive, so they're kept in upper case for lookups
+	k = strings.ToUpper(contentLine[p : p+tokenPos[1]])
 	p += tokenPos[1]
 	switch rune(contentLine[p]) {
 	case '=':
@@ -291,11 +535,19 @@ func parsePropertyValue(r *BaseProperty, contentLine string, p int) *BasePropert
 
 var textEscaper = strings.NewReplacer(
 	`\`, `\\`,
+	"\r\n", `\n`,
 	"\n", `\n`,
+	"\r", `\n`,
 	`;`, `\;`,
 	`,`, `\,`,
 )
 
+var lineBreakEscaper = strings.NewReplacer(
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
 func ToText(s string) string {
 	// Some special characters for iCalendar format should be escaped while
 	// setting a value of a property with a TEXT type.
@@ -315,3 +567,240 @@ func FromText(s string) string {
 	// setting a value of a property with a TEXT type.
 	return textUnescaper.Replace(s)
 }
+
+// Period is a PERIOD value as described in RFC 5545 §3.3.9. Periods written as a start and a duration are resolved to
+// their end time.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (p Period) String() string {
+	return p.Start.UTC().Format(icalTimestampFormatUtc) + "/" + p.End.UTC().Format(icalTimestampFormatUtc)
+}
+
+// ParsePeriods parses a comma separated list of PERIOD values, such as the value of a FREEBUSY property.
+func ParsePeriods(s string) ([]Period, error) {
+	r := []Period{}
+	for _, v := range strings.Split(s, ",") {
+		p, err := parsePeriod(strings.TrimSpace(v), nil)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, p)
+	}
+	return r, nil
+}
+
+func parsePeriod(s string, params map[string][]string) (Period, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Period{}, fmt.Errorf("malformed period '%s'", s)
+	}
+	start, err := parseTimeValue(parts[0], params, false, nil)
+	if err != nil {
+		return Period{}, fmt.Errorf("parsing period start: %w", err)
+	}
+	var end time.Time
+	if strings.HasPrefix(parts[1], "P") || strings.HasPrefix(parts[1], "+P") {
+		d, err := parseDuration(parts[1])
+		if err != nil {
+			return Period{}, fmt.Errorf("parsing period duration: %w", err)
+		}
+		end = start.Add(d)
+	} else {
+		end, err = parseTimeValue(parts[1], params, false, nil)
+		if err != nil {
+			return Period{}, fmt.Errorf("parsing period end: %w", err)
+		}
+	}
+	return Period{Start: start, End: end}, nil
+}
+
+// parseDuration parses a DURATION value as described in RFC 5545 §3.3.6, such as "PT1H30M", "-P2D" or "P1W". Days and
+// weeks are treated as exactly 24 and 168 hours.
+func parseDuration(s string) (time.Duration, error) {
+	v := s
+	neg := false
+	switch {
+	case strings.HasPrefix(v, "-"):
+		neg = true
+		v = v[1:]
+	case strings.HasPrefix(v, "+"):
+		v = v[1:]
+	}
+	if !strings.HasPrefix(v, "P") || len(v) < 3 {
+		return 0, fmt.Errorf("malformed duration '%s'", s)
+	}
+	v = v[1:]
+	var d time.Duration
+	inTime := false
+	n := -1
+	for _, c := range v {
+		switch {
+		case c >= '0' && c <= '9':
+			if n < 0 {
+				n = 0
+			}
+			n = n*10 + int(c-'0')
+			continue
+		case c == 'T' && !inTime && n < 0:
+			inTime = true
+			continue
+		}
+		if n < 0 {
+			return 0, fmt.Errorf("malformed duration '%s'", s)
+		}
+		unit := time.Duration(0)
+		switch {
+		case c == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case c == 'H' && inTime:
+			unit = time.Hour
+		case c == 'M' && inTime:
+			unit = time.Minute
+		case c == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("malformed duration '%s'", s)
+		}
+		d += time.Duration(n) * unit
+		n = -1
+	}
+	if n >= 0 {
+		return 0, errors.New("duration ends without a unit")
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// formatDuration formats d as a DURATION value. Whole days are written as days, the remainder as hours, minutes and
+// seconds; fractions of a second are dropped.
+func formatDuration(d time.Duration) string {
+	b := &strings.Builder{}
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("P")
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	sec := d / time.Second
+	if days > 0 {
+		fmt.Fprintf(b, "%dD", days)
+	}
+	if h > 0 || m > 0 || sec > 0 || days == 0 {
+		b.WriteString("T")
+		if h > 0 {
+			fmt.Fprintf(b, "%dH", h)
+		}
+		if m > 0 {
+			fmt.Fprintf(b, "%dM", m)
+		}
+		if sec > 0 || (h == 0 && m == 0) {
+			fmt.Fprintf(b, "%dS", sec)
+		}
+	}
+	return b.String()
+}
+
+var propertyValueDataTypes = map[Property]ValueDataType{
+	PropertyCalscale:             ValueDataTypeText,
+	PropertyMethod:               ValueDataTypeText,
+	PropertyProductId:            ValueDataTypeText,
+	PropertyVersion:              ValueDataTypeText,
+	Property("REFRESH-INTERVAL"): ValueDataTypeDuration,
+	PropertyAttach:               ValueDataTypeUri,
+	PropertyCategories:           ValueDataTypeText,
+	PropertyClass:                ValueDataTypeText,
+	PropertyColor:                ValueDataTypeText,
+	PropertyComment:              ValueDataTypeText,
+	PropertyDescription:          ValueDataTypeText,
+	PropertyConference:           ValueDataTypeUri,
+	PropertyGeo:                  ValueDataTypeFloat,
+	PropertySource:               ValueDataTypeUri,
+	PropertyAcknowledged:         ValueDataTypeDateTime,
+	PropertyStructuredLocation:   ValueDataTypeUri,
+	PropertyImage:                ValueDataTypeUri,
+	PropertyLocation:             ValueDataTypeText,
+	PropertyPercentComplete:      ValueDataTypeInteger,
+	PropertyPriority:             ValueDataTypeInteger,
+	PropertyResources:            ValueDataTypeText,
+	PropertyStatus:               ValueDataTypeText,
+	PropertySummary:              ValueDataTypeText,
+	PropertyCompleted:            ValueDataTypeDateTime,
+	PropertyDtend:                ValueDataTypeDateTime,
+	PropertyDue:                  ValueDataTypeDateTime,
+	PropertyDtstart:              ValueDataTypeDateTime,
+	PropertyDuration:             ValueDataTypeDuration,
+	PropertyFreebusy:             ValueDataTypePeriod,
+	PropertyTransp:               ValueDataTypeText,
+	PropertyTzid:                 ValueDataTypeText,
+	PropertyTzname:               ValueDataTypeText,
+	PropertyTzoffsetfrom:         ValueDataTypeUtcOffset,
+	PropertyTzoffsetto:           ValueDataTypeUtcOffset,
+	PropertyTzurl:                ValueDataTypeUri,
+	PropertyAttendee:             ValueDataTypeCalAddress,
+	PropertyContact:              ValueDataTypeText,
+	PropertyOrganizer:            ValueDataTypeCalAddress,
+	PropertyRecurrenceId:         ValueDataTypeDateTime,
+	PropertyRelatedTo:            ValueDataTypeText,
+	PropertyUrl:                  ValueDataTypeUri,
+	PropertyUid:                  ValueDataTypeText,
+	PropertyExdate:               ValueDataTypeDateTime,
+	PropertyExrule:               ValueDataTypeRecur,
+	PropertyRdate:                ValueDataTypeDateTime,
+	PropertyRrule:                ValueDataTypeRecur,
+	PropertyAction:               ValueDataTypeText,
+	PropertyRepeat:               ValueDataTypeInteger,
+	PropertyTrigger:              ValueDataTypeDuration,
+	PropertyCreated:              ValueDataTypeDateTime,
+	PropertyDtstamp:              ValueDataTypeDateTime,
+	PropertyLastModified:         ValueDataTypeDateTime,
+	PropertyRequestStatus:        ValueDataTypeText,
+	PropertyName:                 ValueDataTypeText,
+	PropertySequence:             ValueDataTypeInteger,
+}
+
+// defaultValueDataType returns the value type a property has when it carries no VALUE parameter, and whether the
+// property is one this library knows the type of.
+func defaultValueDataType(property string) (ValueDataType, bool) {
+	t, ok := propertyValueDataTypes[Property(strings.ToUpper(property))]
+	if !ok {
+		return ValueDataTypeText, false
+	}
+	return t, true
+}
+
+// valueDataType returns the value type of the property, taking the VALUE parameter into account.
+func (property *BaseProperty) valueDataType() ValueDataType {
+	if v := property.ICalParameters[string(ParameterValue)]; len(v) > 0 {
+		return ValueDataType(strings.ToUpper(v[0]))
+	}
+	t, _ := defaultValueDataType(property.IANAToken)
+	return t
+}
+
+// splitUnescaped splits s around every sep which isn't escaped with a backslash. Escapes are left in place.
+func splitUnescaped(s string, sep byte) []string {
+	var r []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			r = append(r, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(r, s[start:])
+}
@@ -1,7 +1,11 @@
 package ics
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -185,3 +189,206 @@ func Test_parsePropertyParamValue(t *testing.T) {
 		})
 	}
 }
+
+func TestDuration(t *testing.T) {
+	for _, tc := range []struct {
+		Input    string
+		Expected time.Duration
+		Output   string
+	}{
+		{"PT1H30M", 90 * time.Minute, "PT1H30M"},
+		{"P1D", 24 * time.Hour, "P1D"},
+		{"P1W", 7 * 24 * time.Hour, "P7D"},
+		{"-PT15M", -15 * time.Minute, "-PT15M"},
+		{"+P1DT2H3M4S", 26*time.Hour + 3*time.Minute + 4*time.Second, "P1DT2H3M4S"},
+		{"PT0S", 0, "PT0S"},
+	} {
+		d, err := parseDuration(tc.Input)
+		assert.NoError(t, err, tc.Input)
+		assert.Equal(t, tc.Expected, d, tc.Input)
+		assert.Equal(t, tc.Output, formatDuration(d), tc.Input)
+	}
+	for _, s := range []string{"", "P", "1H", "PT1D", "P1H", "PT1", "PTXH"} {
+		_, err := parseDuration(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestWriteProperty(t *testing.T) {
+	b := &bytes.Buffer{}
+	value := strings.Repeat("日本語のテキスト ", 12)
+	err := WriteProperty(b, "DESCRIPTION", map[string][]string{
+		"LANGUAGE": {"ja"},
+		"ALTREP":   {"https://example.com/a/long/path/to/the/alternate/representation"},
+	}, value)
+	assert.NoError(t, err)
+
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, `DESCRIPTION;ALTREP="https://example.com/`), out)
+	assert.True(t, strings.HasSuffix(out, "\r\n"))
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	assert.True(t, len(lines) > 1)
+	for _, l := range lines {
+		assert.True(t, len(l) <= 75, l)
+		assert.True(t, utf8.ValidString(l), l)
+		assert.NotContains(t, l, "\n")
+	}
+
+	unfolded := strings.Replace(strings.TrimSuffix(out, "\r\n"), "\r\n ", "", -1)
+	p, err := ParseProperty(ContentLine(unfolded))
+	if assert.NoError(t, err) {
+		assert.Equal(t, value, p.Value)
+		assert.Equal(t, []string{"ja"}, p.ICalParameters["LANGUAGE"])
+	}
+}
+
+func TestPropertyParamQuotingRoundTrip(t *testing.T) {
+	for _, v := range []string{
+		"plain",
+		"Doe, Jane",
+		"mailto:jane@example.com",
+		"a;b",
+		`say "hi"`,
+		`back\slash`,
+		`all ;:, "\ together`,
+	} {
+		b := &bytes.Buffer{}
+		assert.NoError(t, WriteProperty(b, "ATTENDEE", map[string][]string{"CN": {v}}, "mailto:jane@example.com"))
+		line := strings.TrimSuffix(b.String(), "\r\n")
+		if strings.ContainsAny(v, ";:,") {
+			assert.Contains(t, line, `CN="`, v)
+		}
+		p, err := ParseProperty(ContentLine(line))
+		if assert.NoError(t, err, line) {
+			assert.Equal(t, []string{v}, p.ICalParameters["CN"], line)
+			assert.Equal(t, "mailto:jane@example.com", p.Value, line)
+		}
+	}
+}
+
+func TestTextLineBreaks(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne\nf\ng`, ToText("a\\b;c,d\ne\r\nf\rg"))
+	assert.Equal(t, "a\\b;c,d\ne\nf\ng", FromText(ToText("a\\b;c,d\ne\r\nf\rg")))
+
+	e := NewEvent("line-breaks")
+	e.SetDescription("line1\nline2\r\nline3")
+	e.SetProperty(ComponentProperty("X-NOTES"), "raw1\nraw2")
+	s := e.Serialize()
+	assert.Contains(t, s, "DESCRIPTION:line1\\nline2\\nline3\r\n")
+	assert.Contains(t, s, "X-NOTES:raw1\\nraw2\r\n")
+	assert.NotContains(t, strings.Replace(s, "\r\n", "", -1), "\n")
+
+	parsed, err := ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\r\n" + s + "END:VCALENDAR\r\n"))
+	if assert.NoError(t, err) {
+		p := parsed.Events()[0].GetProperty(ComponentPropertyDescription)
+		assert.Equal(t, "line1\nline2\nline3", FromText(p.Value))
+	}
+}
+
+func TestParameterConstants(t *testing.T) {
+	p, err := ParseProperty(`STRUCTURED-DATA;order=2;SCHEMA="https://schema.org/Event";DERIVED=TRUE;VALUE=URI:https://example.com/event.json`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2"}, p.ICalParameters[string(ParameterOrder)])
+		assert.Equal(t, []string{"https://schema.org/Event"}, p.ICalParameters[string(ParameterSchema)])
+		assert.Equal(t, []string{"TRUE"}, p.ICalParameters[string(ParameterDerived)])
+	}
+	p, err = ParseProperty(`ATTENDEE;EMAIL=jane@example.com:mailto:jdoe@example.com`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"jane@example.com"}, p.ICalParameters[string(ParameterEmail)])
+	}
+}
+
+func TestTransparentEncoding(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nUID:1\r\n" +
+		"SUMMARY;ENCODING=QUOTED-PRINTABLE;CHARSET=UTF-8:Caf=C3=A9 meeting\r\n" +
+		"DESCRIPTION;ENCODING=QUOTED-PRINTABLE:First line=0D=0ASecond line\r\n" +
+		"LOCATION;ENCODING=BASE64:Um9vbSAx\r\n" +
+		"ATTACH;ENCODING=BASE64;VALUE=BINARY:aGVsbG8=\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	e := cal.Events()[0]
+	summary, err := e.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "Café meeting", summary)
+	description, err := e.GetDescription()
+	assert.NoError(t, err)
+	assert.Equal(t, "First line\r\nSecond line", description)
+	location, err := e.GetLocation()
+	assert.NoError(t, err)
+	assert.Equal(t, "Room 1", location)
+	attachments, err := e.GetAttachments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Attachment{{IsInline: true, Data: []byte("hello")}}, attachments)
+
+	s := cal.Serialize()
+	assert.Contains(t, s, "SUMMARY;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:Caf=C3=A9 meeting\r\n")
+	assert.Contains(t, s, "DESCRIPTION;ENCODING=QUOTED-PRINTABLE:First line=0D=0ASecond line\r\n")
+	assert.Contains(t, s, "LOCATION;ENCODING=BASE64:Um9vbSAx\r\n")
+	assert.Contains(t, s, "ATTACH;ENCODING=BASE64;VALUE=BINARY:aGVsbG8=\r\n")
+
+	e.SetProperty(ComponentPropertyDescription, "", WithEncoding("QUOTED-PRINTABLE"))
+	e.GetProperty(ComponentPropertyDescription).SetDecodedValue(strings.Repeat("Ünïcödé ", 20))
+	reparsed, err := ParseCalendar(strings.NewReader(cal.Serialize()))
+	if assert.NoError(t, err) {
+		description, err = reparsed.Events()[0].GetDescription()
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("Ünïcödé ", 20), description)
+	}
+
+	// Values set already encoded are written as they are
+	e.SetProperty("X-FOO", "aGVsbG8=", WithEncoding("BASE64"))
+	assert.Contains(t, e.Serialize(), "X-FOO;ENCODING=BASE64:aGVsbG8=\r\n")
+	decoded, err := e.GetProperty("X-FOO").DecodedValue()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", decoded)
+}
+
+func TestTransparentEncodingLegacy(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nUID:1\r\n" +
+		"SUMMARY;ENCODING=QUOTED-PRINTABLE;CHARSET=ISO-8859-1:Caf=E9 =\r\nmeeting\r\n" +
+		"DESCRIPTION;CHARSET=windows-1252;ENCODING=QUOTED-PRINTABLE:=93Quoted=94 =\r\n=80\r\n 5\r\n" +
+		"LOCATION;ENCODING=QUOTED-PRINTABLE;CHARSET=SHIFT_JIS:=82=A0\r\n" +
+		"COMMENT:ends with =\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	e := cal.Events()[0]
+	summary, err := e.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "Café meeting", summary)
+	description, err := e.GetDescription()
+	assert.NoError(t, err)
+	assert.Equal(t, "\u201cQuoted\u201d \u20ac5", description)
+	_, err = e.GetProperty(ComponentPropertyLocation).DecodedValue()
+	assert.Error(t, err)
+	assert.Equal(t, "ends with =", e.GetProperty(ComponentPropertyComment).Value)
+
+	e.GetProperty(ComponentPropertySummary).SetDecodedValue("Déjà vu")
+	assert.Nil(t, e.GetProperty(ComponentPropertySummary).ICalParameters[string(ParameterCharset)])
+	summary, err = e.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, "Déjà vu", summary)
+}
+
+func TestTransparentEncodingInvalid(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nUID:1\r\n" +
+		"DTSTAMP:20240101T000000Z\r\nSUMMARY;ENCODING=BASE64:oops!\r\nX-THING;ENCODING=BASE64:oops!\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if assert.NoError(t, err) {
+		e := cal.Events()[0]
+		summary, err := e.GetSummary()
+		assert.NoError(t, err)
+		assert.Equal(t, "oops!", summary)
+		_, err = e.GetProperty(ComponentPropertySummary).DecodedValue()
+		assert.Error(t, err)
+		assert.Contains(t, cal.Serialize(), "X-THING;ENCODING=BASE64:oops!\r\n")
+	}
+	_, err = ParseCalendar(strings.NewReader(input), WithStrictMode())
+	assert.Error(t, err)
+}
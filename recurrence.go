@@ -0,0 +1,817 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencySecondly Frequency = "SECONDLY"
+	FrequencyMinutely Frequency = "MINUTELY"
+	FrequencyHourly   Frequency = "HOURLY"
+	FrequencyDaily    Frequency = "DAILY"
+	FrequencyWeekly   Frequency = "WEEKLY"
+	FrequencyMonthly  Frequency = "MONTHLY"
+	FrequencyYearly   Frequency = "YEARLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is a single BYDAY entry, such as "MO" or "-1SU". An Ordinal of 0 matches every such weekday in the period.
+type WeekdayNum struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// RecurrenceRule is the parsed form of an RRULE value as described in RFC 5545 §3.3.10.
+type RecurrenceRule struct {
+	Frequency  Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	WeekStart  time.Weekday
+	ByDay      []WeekdayNum
+	ByMonth    []int
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByHour     []int
+	ByMinute   []int
+	BySecond   []int
+	BySetPos   []int
+
+	// untilFloating records an UNTIL without a UTC designator, which is interpreted in the zone of DTSTART.
+	untilFloating bool
+}
+
+// ErrUnsupportedRecurrenceRule is returned when expanding a valid recurrence rule using a rule part the expander
+// doesn't support.
+var ErrUnsupportedRecurrenceRule = errors.New("unsupported recurrence rule")
+
+func ParseRecurrenceRule(s string) (*RecurrenceRule, error) {
+	r := &RecurrenceRule{
+		Interval:  1,
+		WeekStart: time.Monday,
+	}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("malformed recurrence rule part '%s'", part)
+		}
+		k, v := strings.ToUpper(kv[0]), kv[1]
+		var err error
+		switch k {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(v)); f {
+			case FrequencySecondly, FrequencyMinutely, FrequencyHourly, FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				r.Frequency = f
+			default:
+				return nil, fmt.Errorf("unknown recurrence frequency '%s'", v)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(v)
+			if err == nil && r.Interval < 1 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(v)
+			if err == nil && r.Count < 1 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			r.Until, err = parseTimeValue(v, nil, false, nil)
+			if err == nil && !strings.HasSuffix(v, "Z") {
+				r.untilFloating = true
+				if len(v) == len(icalDateFormatLocal) {
+					// A date-only UNTIL includes the whole of that day
+					r.Until = r.Until.Add(24*time.Hour - time.Second)
+				}
+			}
+		case "WKST":
+			wd, ok := weekdayCodes[strings.ToUpper(v)]
+			if !ok {
+				err = errors.New("unknown weekday")
+			}
+			r.WeekStart = wd
+		case "BYDAY":
+			r.ByDay, err = parseWeekdayNumList(v)
+		case "BYMONTH":
+			r.ByMonth, err = parseIntList(v, 1, 12, false)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseIntList(v, 1, 31, true)
+		case "BYWEEKNO":
+			r.ByWeekNo, err = parseIntList(v, 1, 53, true)
+		case "BYSETPOS":
+			r.BySetPos, err = parseIntList(v, 1, 366, true)
+		case "BYHOUR":
+			r.ByHour, err = parseIntList(v, 0, 23, false)
+		case "BYMINUTE":
+			r.ByMinute, err = parseIntList(v, 0, 59, false)
+		case "BYSECOND":
+			r.BySecond, err = parseIntList(v, 0, 60, false)
+		case "BYYEARDAY":
+			r.ByYearDay, err = parseIntList(v, 1, 366, true)
+		default:
+			// Unknown and X- rule parts are ignored
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing recurrence rule part %s: %w", k, err)
+		}
+	}
+	if r.Frequency == "" {
+		return nil, errors.New("recurrence rule is missing FREQ")
+	}
+	if len(r.ByWeekNo) > 0 && r.Frequency != FrequencyYearly {
+		return nil, errors.New("recurrence rule part BYWEEKNO is only allowed with FREQ=YEARLY")
+	}
+	if len(r.ByYearDay) > 0 && (r.Frequency == FrequencyDaily || r.Frequency == FrequencyWeekly || r.Frequency == FrequencyMonthly) {
+		return nil, fmt.Errorf("recurrence rule part BYYEARDAY isn't allowed with FREQ=%s", r.Frequency)
+	}
+	return r, nil
+}
+
+func parseIntList(s string, min int, max int, allowNegative bool) ([]int, error) {
+	var r []int
+	for _, v := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		a := i
+		if a < 0 && allowNegative {
+			a = -a
+		}
+		if a < min || a > max {
+			return nil, fmt.Errorf("value %d out of range", i)
+		}
+		r = append(r, i)
+	}
+	return r, nil
+}
+
+func parseWeekdayNumList(s string) ([]WeekdayNum, error) {
+	var r []WeekdayNum
+	for _, v := range strings.Split(s, ",") {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if len(v) < 2 {
+			return nil, fmt.Errorf("malformed weekday '%s'", v)
+		}
+		wd, ok := weekdayCodes[v[len(v)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday '%s'", v)
+		}
+		wn := WeekdayNum{Weekday: wd}
+		if len(v) > 2 {
+			n, err := strconv.Atoi(v[:len(v)-2])
+			if err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, fmt.Errorf("malformed weekday ordinal '%s'", v)
+			}
+			wn.Ordinal = n
+		}
+		r = append(r, wn)
+	}
+	return r, nil
+}
+
+// checkSupported returns ErrUnsupportedRecurrenceRule if the rule uses a rule part iterate doesn't handle.
+func (rule *RecurrenceRule) checkSupported() error {
+	if len(rule.ByYearDay) > 0 {
+		return fmt.Errorf("%w: part BYYEARDAY", ErrUnsupportedRecurrenceRule)
+	}
+	return nil
+}
+
+// iterate calls fn with every occurrence of the rule in chronological order, beginning with dtstart which always counts
+// as the first occurrence. Iteration stops once fn returns false, the rule is exhausted, or the rule has moved past
+// end.
+func (rule *RecurrenceRule) iterate(dtstart time.Time, end time.Time, fn func(time.Time) bool) {
+	until := rule.Until
+	if rule.untilFloating {
+		until = time.Date(until.Year(), until.Month(), until.Day(), until.Hour(), until.Minute(), until.Second(), 0, dtstart.Location())
+	}
+	count := 0
+	emit := func(t time.Time) bool {
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+		count++
+		if !fn(t) {
+			return false
+		}
+		return rule.Count == 0 || count < rule.Count
+	}
+	if !emit(dtstart) {
+		return
+	}
+	x := rule.withDefaults(dtstart)
+	for n := 0; ; n += x.Interval {
+		periodStart := x.periodStart(dtstart, n)
+		if periodStart.After(end) || (!until.IsZero() && periodStart.After(until)) {
+			return
+		}
+		for _, t := range x.periodOccurrences(dtstart, periodStart) {
+			if !t.After(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// withDefaults returns a copy of the rule with the BYxxx parts that are implied by dtstart filled in.
+func (rule *RecurrenceRule) withDefaults(dtstart time.Time) *RecurrenceRule {
+	x := *rule
+	if x.Interval < 1 {
+		x.Interval = 1
+	}
+	if len(x.ByMonthDay) == 0 && len(x.ByDay) == 0 && len(x.ByWeekNo) == 0 {
+		switch x.Frequency {
+		case FrequencyYearly:
+			if len(x.ByMonth) == 0 {
+				x.ByMonth = []int{int(dtstart.Month())}
+			}
+			x.ByMonthDay = []int{dtstart.Day()}
+		case FrequencyMonthly:
+			x.ByMonthDay = []int{dtstart.Day()}
+		case FrequencyWeekly:
+			x.ByDay = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+	}
+	return &x
+}
+
+// periodStart returns the beginning of the n-th period counted from the one containing dtstart.
+func (rule *RecurrenceRule) periodStart(dtstart time.Time, n int) time.Time {
+	loc := dtstart.Location()
+	y, m, d := dtstart.Date()
+	switch rule.Frequency {
+	case FrequencyYearly:
+		return time.Date(y+n, 1, 1, 0, 0, 0, 0, loc)
+	case FrequencyMonthly:
+		return time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, loc)
+	case FrequencyWeekly:
+		offset := (int(dtstart.Weekday()) - int(rule.WeekStart) + 7) % 7
+		return time.Date(y, m, d-offset+7*n, 0, 0, 0, 0, loc)
+	case FrequencyDaily:
+		return time.Date(y, m, d+n, 0, 0, 0, 0, loc)
+	case FrequencyHourly:
+		return dtstart.Add(time.Duration(n) * time.Hour)
+	case FrequencyMinutely:
+		return dtstart.Add(time.Duration(n) * time.Minute)
+	default:
+		return dtstart.Add(time.Duration(n) * time.Second)
+	}
+}
+
+// periodOccurrences returns the sorted occurrences within the period beginning at periodStart, with BYSETPOS applied.
+// BYHOUR, BYMINUTE and BYSECOND limit the periods of rules at least as frequent as them and otherwise expand each day
+// matched, or the period, into those times.
+func (rule *RecurrenceRule) periodOccurrences(dtstart time.Time, periodStart time.Time) []time.Time {
+	var r []time.Time
+	hours := defaultInts(rule.ByHour, dtstart.Hour())
+	minutes := defaultInts(rule.ByMinute, dtstart.Minute())
+	seconds := defaultInts(rule.BySecond, dtstart.Second())
+	switch rule.Frequency {
+	case FrequencyHourly, FrequencyMinutely, FrequencySecondly:
+		if !rule.matchesDay(periodStart) || (len(rule.ByHour) > 0 && !containsInt(rule.ByHour, periodStart.Hour())) {
+			break
+		}
+		hours = []int{periodStart.Hour()}
+		if rule.Frequency != FrequencyHourly {
+			if len(rule.ByMinute) > 0 && !containsInt(rule.ByMinute, periodStart.Minute()) {
+				break
+			}
+			minutes = []int{periodStart.Minute()}
+		}
+		if rule.Frequency == FrequencySecondly {
+			if len(rule.BySecond) > 0 && !containsInt(rule.BySecond, periodStart.Second()) {
+				break
+			}
+			seconds = []int{periodStart.Second()}
+		}
+		r = expandTimes(periodStart, hours, minutes, seconds)
+	default:
+		y, m, d := periodStart.Date()
+		var days int
+		switch rule.Frequency {
+		case FrequencyYearly:
+			days = daysIn(y, 0)
+		case FrequencyMonthly:
+			days = daysIn(y, m)
+		case FrequencyWeekly:
+			days = 7
+		default:
+			days = 1
+		}
+		for i := 0; i < days; i++ {
+			day := time.Date(y, m, d+i, 0, 0, 0, 0, dtstart.Location())
+			if rule.matchesDay(day) {
+				r = append(r, expandTimes(day, hours, minutes, seconds)...)
+			}
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Before(r[j])
+	})
+	return rule.applySetPos(r)
+}
+
+// expandTimes returns every combination of the hours, minutes and seconds on the day of t.
+func expandTimes(t time.Time, hours, minutes, seconds []int) []time.Time {
+	y, m, d := t.Date()
+	r := make([]time.Time, 0, len(hours)*len(minutes)*len(seconds))
+	for _, h := range hours {
+		for _, min := range minutes {
+			for _, sec := range seconds {
+				r = append(r, time.Date(y, m, d, h, min, sec, 0, t.Location()))
+			}
+		}
+	}
+	return r
+}
+
+func defaultInts(l []int, v int) []int {
+	if len(l) == 0 {
+		return []int{v}
+	}
+	return l
+}
+
+func (rule *RecurrenceRule) matchesDay(t time.Time) bool {
+	y, m, d := t.Date()
+	if len(rule.ByMonth) > 0 && !containsInt(rule.ByMonth, int(m)) {
+		return false
+	}
+	if len(rule.ByWeekNo) > 0 {
+		year, week := weekNumber(t, rule.WeekStart)
+		weeks := weeksIn(year, rule.WeekStart)
+		match := false
+		for _, wn := range rule.ByWeekNo {
+			if wn == week || (wn < 0 && weeks+wn+1 == week) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	if len(rule.ByMonthDay) > 0 {
+		dim := daysIn(y, m)
+		match := false
+		for _, md := range rule.ByMonthDay {
+			if md == d || (md < 0 && dim+md+1 == d) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	if len(rule.ByDay) > 0 {
+		match := false
+		for _, wn := range rule.ByDay {
+			if wn.Weekday == t.Weekday() && (wn.Ordinal == 0 || rule.matchesOrdinal(t, wn.Ordinal)) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesOrdinal reports whether t is the n-th of its weekday in the month, or in the year for YEARLY rules without
+// BYMONTH.
+func (rule *RecurrenceRule) matchesOrdinal(t time.Time, n int) bool {
+	y, m, _ := t.Date()
+	var pos, size int
+	switch {
+	case rule.Frequency == FrequencyMonthly || (rule.Frequency == FrequencyYearly && len(rule.ByMonth) > 0):
+		pos, size = t.Day(), daysIn(y, m)
+	case rule.Frequency == FrequencyYearly:
+		pos, size = t.YearDay(), daysIn(y, 0)
+	default:
+		// Ordinals are only meaningful for MONTHLY and YEARLY rules
+		return true
+	}
+	if n > 0 {
+		return (pos-1)/7+1 == n
+	}
+	return -((size-pos)/7 + 1) == n
+}
+
+func (rule *RecurrenceRule) applySetPos(set []time.Time) []time.Time {
+	if len(rule.BySetPos) == 0 || len(set) == 0 {
+		return set
+	}
+	var r []time.Time
+	for i := range set {
+		if containsInt(rule.BySetPos, i+1) || containsInt(rule.BySetPos, i-len(set)) {
+			r = append(r, set[i])
+		}
+	}
+	return r
+}
+
+// weekNumber returns the week of t and the year that week belongs to, with weeks starting on wkst. As in ISO 8601, week
+// 1 is the first week with at least four days in the year, so the first days of January may be in the last week of the
+// year before and the last days of December in week 1 of the year after.
+func weekNumber(t time.Time, wkst time.Weekday) (year int, week int) {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	year = y
+	start := weekOneStart(year, wkst)
+	if day.Before(start) {
+		year--
+		start = weekOneStart(year, wkst)
+	} else if next := weekOneStart(year+1, wkst); !day.Before(next) {
+		year++
+		start = next
+	}
+	return year, int(day.Sub(start).Hours())/(24*7) + 1
+}
+
+// weeksIn returns the number of weeks in the year, 52 or 53, with weeks starting on wkst.
+func weeksIn(year int, wkst time.Weekday) int {
+	return int(weekOneStart(year+1, wkst).Sub(weekOneStart(year, wkst)).Hours()) / (24 * 7)
+}
+
+// weekOneStart returns the first day, in UTC, of week 1 of the year.
+func weekOneStart(year int, wkst time.Weekday) time.Time {
+	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan1.Weekday()) - int(wkst) + 7) % 7
+	if offset > 3 {
+		// The week containing January 1st has fewer than four days in the year
+		offset -= 7
+	}
+	return jan1.AddDate(0, 0, -offset)
+}
+
+// daysIn returns the number of days in the given month, or in the whole year when month is 0.
+func daysIn(year int, month time.Month) int {
+	if month == 0 {
+		return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func containsInt(l []int, v int) bool {
+	for _, i := range l {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// RRuleExpand returns the start of every occurrence of the event that falls between from and to inclusive. Events
+// without an RRULE have a single occurrence at DTSTART; those with several RRULEs have the occurrences of each. RDATEs
+// are added to the occurrences, in order. Occurrences matching an EXDATE are left out; a DATE valued EXDATE excludes
+// every occurrence on that day. Occurrences overridden by another event of the calendar with the same UID and a
+// RECURRENCE-ID are moved to the DTSTART of the override, and are returned when that is in range wherever the original
+// occurrence was.
+func (event *VEvent) RRuleExpand(from, to time.Time) ([]time.Time, error) {
+	occurrences, err := event.expand(from, to)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]time.Time, len(occurrences))
+	for i, o := range occurrences {
+		r[i] = o.start
+	}
+	return r, nil
+}
+
+// occurrence is an instance of a recurring event once overrides are applied. The override is the event overriding the
+// instance, or nil when the instance is as the event describes it.
+type occurrence struct {
+	start    time.Time
+	override *VEvent
+}
+
+// expand returns the occurrences of the event as RRuleExpand does, along with the events overriding them.
+func (event *VEvent) expand(from, to time.Time) ([]occurrence, error) {
+	start, err := event.GetStartAt()
+	if err != nil {
+		return nil, err
+	}
+	exdates, err := event.exDates()
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := event.overrides()
+	if err != nil {
+		return nil, err
+	}
+	// Occurrences after to can still be moved into the range by an override
+	end := overrides.end(to)
+	r := []occurrence{}
+	seen := map[int64]bool{}
+	add := func(t time.Time) bool {
+		if t.After(end) {
+			return false
+		}
+		if seen[t.UnixNano()] {
+			return true
+		}
+		seen[t.UnixNano()] = true
+		if excluded(exdates, t) {
+			return true
+		}
+		if t, override := overrides.apply(t); !t.Before(from) && !t.After(to) {
+			r = append(r, occurrence{start: t, override: override})
+		}
+		return true
+	}
+	rrules := event.GetRRules()
+	if len(rrules) == 0 {
+		add(start)
+	}
+	for _, v := range rrules {
+		rule, err := ParseRecurrenceRule(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := rule.checkSupported(); err != nil {
+			return nil, err
+		}
+		rule.iterate(start, end, add)
+	}
+	rdates, err := event.GetRDates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range rdates {
+		add(t)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].start.Before(r[j].start)
+	})
+	return r, nil
+}
+
+// GetRRule returns the first RRULE of the event, or "" if it has none. See GetRRules for events with several, which RFC
+// 5545 allows though deprecates.
+func (event *VEvent) GetRRule() string {
+	if rrules := event.GetRRules(); len(rrules) > 0 {
+		return rrules[0]
+	}
+	return ""
+}
+
+// GetRRules returns the values of all RRULE properties of the event, in order.
+func (event *VEvent) GetRRules() []string {
+	var r []string
+	for _, p := range event.Properties {
+		if p.IANAToken == string(ComponentPropertyRrule) {
+			r = append(r, p.Value)
+		}
+	}
+	return r
+}
+
+// AddRDate adds an occurrence to the recurrence set. The RDATE is a DATE when DTSTART is one and a UTC DATE-TIME
+// otherwise.
+func (event *VEvent) AddRDate(t time.Time, props ...PropertyParameter) {
+	if event.IsAllDay() {
+		props = append(props, WithValue(string(ValueDataTypeDate)))
+		event.AddRdate(t.Format(icalDateFormatLocal), props...)
+		return
+	}
+	event.AddRdate(t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+// AddRDatePeriod adds an occurrence with its own end to the recurrence set, as a PERIOD valued RDATE.
+func (event *VEvent) AddRDatePeriod(start, end time.Time, props ...PropertyParameter) {
+	props = append(props, WithValue(string(ValueDataTypePeriod)))
+	event.AddRdate(Period{Start: start, End: end}.String(), props...)
+}
+
+// GetRDates returns the times of all RDATE properties. For PERIOD values these are the starts of the periods.
+func (event *VEvent) GetRDates() ([]time.Time, error) {
+	var r []time.Time
+	for _, p := range event.Properties {
+		if p.IANAToken != string(ComponentPropertyRdate) {
+			continue
+		}
+		period := p.valueDataType() == ValueDataTypePeriod
+		for _, v := range strings.Split(p.Value, ",") {
+			if period {
+				pr, err := parsePeriod(v, p.ICalParameters)
+				if err != nil {
+					return nil, fmt.Errorf("parsing rdate: %w", err)
+				}
+				r = append(r, pr.Start)
+				continue
+			}
+			t, err := parseTimeValue(v, p.ICalParameters, false, event.calendar)
+			if err != nil {
+				return nil, fmt.Errorf("parsing rdate: %w", err)
+			}
+			r = append(r, t)
+		}
+	}
+	return r, nil
+}
+
+type exDate struct {
+	time.Time
+	dateOnly bool
+}
+
+// AddExDate excludes an occurrence from the recurrence set. The EXDATE is a DATE when DTSTART is one, matching the
+// value type as RFC 5545 requires, and a UTC DATE-TIME otherwise.
+func (event *VEvent) AddExDate(t time.Time, props ...PropertyParameter) {
+	if event.IsAllDay() {
+		props = append(props, WithValue(string(ValueDataTypeDate)))
+		event.AddExdate(t.Format(icalDateFormatLocal), props...)
+		return
+	}
+	event.AddExdate(t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+// GetExDates returns the times of all EXDATE properties. DATE values are returned as midnight of that day.
+func (event *VEvent) GetExDates() ([]time.Time, error) {
+	exdates, err := event.exDates()
+	if err != nil {
+		return nil, err
+	}
+	r := make([]time.Time, 0, len(exdates))
+	for _, ex := range exdates {
+		r = append(r, ex.Time)
+	}
+	return r, nil
+}
+
+// IsAllDay reports whether the event is an all-day one, its DTSTART being a DATE rather than a DATE-TIME.
+func (event *VEvent) IsAllDay() bool {
+	p := event.GetProperty(ComponentPropertyDtStart)
+	if p == nil {
+		return false
+	}
+	if v := p.ICalParameters[string(ParameterValue)]; len(v) > 0 {
+		return strings.EqualFold(v[0], string(ValueDataTypeDate))
+	}
+	return len(strings.TrimSuffix(p.Value, "Z")) == len(icalDateFormatLocal)
+}
+
+func (event *VEvent) exDates() ([]exDate, error) {
+	var r []exDate
+	for _, p := range event.Properties {
+		if p.IANAToken != string(ComponentPropertyExdate) {
+			continue
+		}
+		dateOnly := false
+		if v, ok := p.ICalParameters[string(ParameterValue)]; ok && len(v) == 1 && v[0] == string(ValueDataTypeDate) {
+			dateOnly = true
+		}
+		for _, v := range strings.Split(p.Value, ",") {
+			t, err := parseTimeValue(v, p.ICalParameters, false, event.calendar)
+			if err != nil {
+				return nil, fmt.Errorf("parsing exdate: %w", err)
+			}
+			r = append(r, exDate{t, dateOnly || len(v) == len(icalDateFormatLocal)})
+		}
+	}
+	return r, nil
+}
+
+func excluded(exdates []exDate, t time.Time) bool {
+	for _, ex := range exdates {
+		if ex.dateOnly {
+			y1, m1, d1 := ex.Date()
+			y2, m2, d2 := t.Date()
+			if y1 == y2 && m1 == m2 && d1 == d2 {
+				return true
+			}
+		} else if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRecurrenceID marks the event as overriding the occurrence of a recurring event which starts at t. With
+// thisAndFuture the override applies to every later occurrence as well.
+func (event *VEvent) SetRecurrenceID(t time.Time, thisAndFuture bool, props ...PropertyParameter) {
+	if thisAndFuture {
+		props = append(props, &KeyValues{Key: string(ParameterRange), Value: []string{"THISANDFUTURE"}})
+	}
+	if event.IsAllDay() {
+		props = append(props, WithValue(string(ValueDataTypeDate)))
+		event.SetProperty(ComponentPropertyRecurrenceId, t.Format(icalDateFormatLocal), props...)
+		return
+	}
+	event.SetProperty(ComponentPropertyRecurrenceId, t.UTC().Format(icalTimestampFormatUtc), props...)
+}
+
+// GetRecurrenceID returns the start of the occurrence the event overrides, and whether the override applies to every
+// later occurrence as well.
+func (event *VEvent) GetRecurrenceID() (time.Time, bool, error) {
+	p := event.GetProperty(ComponentPropertyRecurrenceId)
+	if p == nil {
+		return time.Time{}, false, ErrPropertyNotFound
+	}
+	t, err := parseTimeValue(p.Value, p.ICalParameters, false, event.calendar)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("parsing %s: %w", ComponentPropertyRecurrenceId, err)
+	}
+	r := p.ICalParameters[string(ParameterRange)]
+	return t, len(r) > 0 && strings.EqualFold(r[0], "THISANDFUTURE"), nil
+}
+
+type override struct {
+	id            time.Time
+	start         time.Time
+	thisAndFuture bool
+	event         *VEvent
+}
+
+type overrideList []override
+
+// overrides returns the events of the calendar overriding occurrences of the event, ordered by RECURRENCE-ID.
+func (event *VEvent) overrides() (overrideList, error) {
+	if event.calendar == nil {
+		return nil, nil
+	}
+	var r overrideList
+	for _, o := range event.calendar.FindEventsByUID(event.Id()) {
+		if o == event {
+			continue
+		}
+		id, thisAndFuture, err := o.GetRecurrenceID()
+		if errors.Is(err, ErrPropertyNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		start, err := o.GetStartAt()
+		if errors.Is(err, ErrPropertyNotFound) {
+			start = id
+		} else if err != nil {
+			return nil, err
+		}
+		r = append(r, override{id: id, start: start, thisAndFuture: thisAndFuture, event: o})
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].id.Before(r[j].id)
+	})
+	return r, nil
+}
+
+// end returns the latest original start of an occurrence which the overrides could move to to or before.
+func (overrides overrideList) end(to time.Time) time.Time {
+	r := to
+	for _, o := range overrides {
+		if o.start.After(to) {
+			continue
+		}
+		if o.id.After(r) {
+			r = o.id
+		}
+		if shifted := to.Add(o.id.Sub(o.start)); o.thisAndFuture && shifted.After(r) {
+			r = shifted
+		}
+	}
+	return r
+}
+
+// apply returns the start of the occurrence at t once overridden, and the event overriding it if there is one. A
+// THISANDFUTURE override moves later occurrences by the same amount it moves its own, and overrides them too.
+func (overrides overrideList) apply(t time.Time) (time.Time, *VEvent) {
+	var shift time.Duration
+	var event *VEvent
+	for _, o := range overrides {
+		if o.id.Equal(t) {
+			return o.start, o.event
+		}
+		if o.thisAndFuture && o.id.Before(t) {
+			shift = o.start.Sub(o.id)
+			event = o.event
+		}
+	}
+	return t.Add(shift), event
+}
@@ -0,0 +1,394 @@
+package ics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRRuleExpand(t *testing.T) {
+	d := func(year int, month time.Month, day int, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	from := d(1997, 1, 1, 0)
+	to := d(2000, 1, 1, 0)
+
+	testCases := []struct {
+		name     string
+		start    time.Time
+		rrule    string
+		exdates  []string
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "daily for 5 occurrences",
+			start:    d(1997, 9, 2, 9),
+			rrule:    "FREQ=DAILY;COUNT=5",
+			expected: []time.Time{d(1997, 9, 2, 9), d(1997, 9, 3, 9), d(1997, 9, 4, 9), d(1997, 9, 5, 9), d(1997, 9, 6, 9)},
+		},
+		{
+			name:     "every other day until",
+			start:    d(1997, 9, 2, 9),
+			rrule:    "FREQ=DAILY;INTERVAL=2;UNTIL=19970910T090000Z",
+			expected: []time.Time{d(1997, 9, 2, 9), d(1997, 9, 4, 9), d(1997, 9, 6, 9), d(1997, 9, 8, 9), d(1997, 9, 10, 9)},
+		},
+		{
+			name:     "monday of week number 20",
+			start:    d(1997, 5, 12, 9),
+			rrule:    "FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO",
+			expected: []time.Time{d(1997, 5, 12, 9), d(1998, 5, 11, 9), d(1999, 5, 17, 9)},
+		},
+		{
+			name:     "first day of week 1 spanning the year before",
+			start:    d(1997, 12, 29, 9),
+			rrule:    "FREQ=YEARLY;BYWEEKNO=1;BYDAY=MO;COUNT=3",
+			expected: []time.Time{d(1997, 12, 29, 9), d(1999, 1, 4, 9)},
+		},
+		{
+			name:     "last week of the year starting on sunday",
+			start:    d(1997, 12, 28, 9),
+			rrule:    "FREQ=YEARLY;BYWEEKNO=-1;BYDAY=SU;WKST=SU",
+			expected: []time.Time{d(1997, 12, 28, 9), d(1998, 12, 27, 9), d(1999, 12, 26, 9)},
+		},
+		{
+			name:  "every other week on monday, wednesday and friday",
+			start: d(1997, 9, 1, 9),
+			rrule: "FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO,WE,FR;COUNT=7",
+			expected: []time.Time{d(1997, 9, 1, 9), d(1997, 9, 3, 9), d(1997, 9, 5, 9), d(1997, 9, 15, 9), d(1997, 9, 17, 9),
+				d(1997, 9, 19, 9), d(1997, 9, 29, 9)},
+		},
+		{
+			name:     "monthly on the first friday",
+			start:    d(1997, 9, 5, 9),
+			rrule:    "FREQ=MONTHLY;COUNT=4;BYDAY=1FR",
+			expected: []time.Time{d(1997, 9, 5, 9), d(1997, 10, 3, 9), d(1997, 11, 7, 9), d(1997, 12, 5, 9)},
+		},
+		{
+			name:     "monthly on the third to the last day",
+			start:    d(1997, 9, 28, 9),
+			rrule:    "FREQ=MONTHLY;BYMONTHDAY=-3;COUNT=6",
+			expected: []time.Time{d(1997, 9, 28, 9), d(1997, 10, 29, 9), d(1997, 11, 28, 9), d(1997, 12, 29, 9), d(1998, 1, 29, 9), d(1998, 2, 26, 9)},
+		},
+		{
+			name:     "yearly in june and july",
+			start:    d(1997, 6, 10, 9),
+			rrule:    "FREQ=YEARLY;COUNT=4;BYMONTH=6,7",
+			expected: []time.Time{d(1997, 6, 10, 9), d(1997, 7, 10, 9), d(1998, 6, 10, 9), d(1998, 7, 10, 9)},
+		},
+		{
+			name:     "third instance of tuesday, wednesday or thursday",
+			start:    d(1997, 9, 4, 9),
+			rrule:    "FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3",
+			expected: []time.Time{d(1997, 9, 4, 9), d(1997, 10, 7, 9), d(1997, 11, 6, 9)},
+		},
+		{
+			name:  "last working day of the month",
+			start: d(1997, 9, 30, 9),
+			rrule: "FREQ=MONTHLY;COUNT=7;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			expected: []time.Time{d(1997, 9, 30, 9), d(1997, 10, 31, 9), d(1997, 11, 28, 9), d(1997, 12, 31, 9),
+				d(1998, 1, 30, 9), d(1998, 2, 27, 9), d(1998, 3, 31, 9)},
+		},
+		{
+			name:     "exdates are left out",
+			start:    d(1997, 9, 2, 9),
+			rrule:    "FREQ=DAILY;COUNT=5",
+			exdates:  []string{"19970903T090000Z,19970905T090000Z"},
+			expected: []time.Time{d(1997, 9, 2, 9), d(1997, 9, 4, 9), d(1997, 9, 6, 9)},
+		},
+		{
+			name:     "window limits an unbounded rule",
+			start:    d(1997, 9, 2, 9),
+			rrule:    "FREQ=WEEKLY",
+			from:     d(1998, 1, 1, 0),
+			to:       d(1998, 1, 31, 0),
+			expected: []time.Time{d(1998, 1, 6, 9), d(1998, 1, 13, 9), d(1998, 1, 20, 9), d(1998, 1, 27, 9)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewEvent("test-rrule")
+			e.SetStartAt(tc.start)
+			e.AddRrule(tc.rrule)
+			for _, ex := range tc.exdates {
+				e.AddExdate(ex)
+			}
+			f, l := from, to
+			if !tc.from.IsZero() {
+				f, l = tc.from, tc.to
+			}
+			occurrences, err := e.RRuleExpand(f, l)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expected, utcTimes(occurrences))
+		})
+	}
+}
+
+func TestRRuleExpandByTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Every 20 minutes from 9:00 AM to 4:40 PM, from the examples of RFC 5545 section 3.8.5.3
+	var every20Minutes []time.Time
+	for day := 2; day <= 3; day++ {
+		for h := 9; h <= 16; h++ {
+			for m := 0; m < 60; m += 20 {
+				every20Minutes = append(every20Minutes, time.Date(1997, 9, day, h, m, 0, 0, ny))
+			}
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		start    time.Time
+		rrule    string
+		to       time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "daily every 20 minutes during the day",
+			start:    time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			rrule:    "FREQ=DAILY;BYHOUR=9,10,11,12,13,14,15,16;BYMINUTE=0,20,40",
+			to:       time.Date(1997, 9, 3, 23, 0, 0, 0, ny),
+			expected: every20Minutes,
+		},
+		{
+			name:     "minutely every 20 minutes during the day",
+			start:    time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			rrule:    "FREQ=MINUTELY;INTERVAL=20;BYHOUR=9,10,11,12,13,14,15,16",
+			to:       time.Date(1997, 9, 3, 23, 0, 0, 0, ny),
+			expected: every20Minutes,
+		},
+		{
+			name:  "every 15 minutes for 6 occurrences",
+			start: time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			rrule: "FREQ=MINUTELY;INTERVAL=15;COUNT=6",
+			to:    time.Date(1997, 9, 3, 0, 0, 0, 0, ny),
+			expected: []time.Time{time.Date(1997, 9, 2, 9, 0, 0, 0, ny), time.Date(1997, 9, 2, 9, 15, 0, 0, ny),
+				time.Date(1997, 9, 2, 9, 30, 0, 0, ny), time.Date(1997, 9, 2, 9, 45, 0, 0, ny),
+				time.Date(1997, 9, 2, 10, 0, 0, 0, ny), time.Date(1997, 9, 2, 10, 15, 0, 0, ny)},
+		},
+		{
+			name:  "weekdays at 9:00 and 14:00",
+			start: time.Date(2024, 3, 1, 9, 0, 0, 0, ny),
+			rrule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=9,14",
+			to:    time.Date(2024, 3, 5, 0, 0, 0, 0, ny),
+			expected: []time.Time{time.Date(2024, 3, 1, 9, 0, 0, 0, ny), time.Date(2024, 3, 1, 14, 0, 0, 0, ny),
+				time.Date(2024, 3, 4, 9, 0, 0, 0, ny), time.Date(2024, 3, 4, 14, 0, 0, 0, ny)},
+		},
+		{
+			name:  "hourly on the quarter hours at 30 seconds",
+			start: time.Date(2024, 3, 1, 9, 0, 30, 0, ny),
+			rrule: "FREQ=HOURLY;BYMINUTE=0,15;BYSECOND=30;COUNT=4",
+			to:    time.Date(2024, 3, 2, 0, 0, 0, 0, ny),
+			expected: []time.Time{time.Date(2024, 3, 1, 9, 0, 30, 0, ny), time.Date(2024, 3, 1, 9, 15, 30, 0, ny),
+				time.Date(2024, 3, 1, 10, 0, 30, 0, ny), time.Date(2024, 3, 1, 10, 15, 30, 0, ny)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewEvent("test")
+			e.SetProperty(ComponentPropertyDtStart, tc.start.Format(icalTimestampFormatLocal),
+				&KeyValues{Key: string(ParameterTzid), Value: []string{ny.String()}})
+			e.AddRrule(tc.rrule)
+			occurrences, err := e.RRuleExpand(tc.start, tc.to)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, utcTimes(tc.expected), utcTimes(occurrences))
+		})
+	}
+}
+
+func TestParseRecurrenceRuleErrors(t *testing.T) {
+	for _, s := range []string{"COUNT=3", "FREQ=FORTNIGHTLY", "FREQ=DAILY;INTERVAL=0", "FREQ=MONTHLY;BYDAY=9XX", "FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;BYWEEKNO=1", "FREQ=YEARLY;BYWEEKNO=54", "FREQ=DAILY;BYHOUR=24", "FREQ=DAILY;BYMINUTE=-1",
+		"FREQ=YEARLY;BYYEARDAY=0", "FREQ=WEEKLY;BYYEARDAY=1"} {
+		_, err := ParseRecurrenceRule(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestByYearDay(t *testing.T) {
+	rule, err := ParseRecurrenceRule("FREQ=YEARLY;BYYEARDAY=1,100,-1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{1, 100, -1}, rule.ByYearDay)
+	}
+
+	e := NewEvent("test-byyearday")
+	e.SetStartAt(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	e.AddRrule("FREQ=YEARLY;BYYEARDAY=1,100")
+	_, err = e.RRuleExpand(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.True(t, errors.Is(err, ErrUnsupportedRecurrenceRule), err)
+}
+
+func TestWeekNumber(t *testing.T) {
+	for _, tc := range []struct {
+		date time.Time
+		wkst time.Weekday
+		year int
+		week int
+	}{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Monday, 2024, 1},
+		{time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), time.Monday, 2025, 1},
+		{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Monday, 2020, 53},
+		{time.Date(2021, 1, 3, 23, 0, 0, 0, time.UTC), time.Monday, 2020, 53},
+		{time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), time.Sunday, 2021, 1},
+	} {
+		year, week := weekNumber(tc.date, tc.wkst)
+		assert.Equal(t, tc.year, year, tc.date.String())
+		assert.Equal(t, tc.week, week, tc.date.String())
+	}
+	assert.Equal(t, 53, weeksIn(2020, time.Monday))
+	assert.Equal(t, 52, weeksIn(2021, time.Monday))
+}
+
+func utcTimes(ts []time.Time) []time.Time {
+	r := make([]time.Time, len(ts))
+	for i := range ts {
+		r[i] = ts[i].UTC()
+	}
+	return r
+}
+
+func TestExDates(t *testing.T) {
+	e := NewEvent("test-exdate")
+	e.SetStartAt(time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC))
+	e.AddRrule("FREQ=DAILY;COUNT=4")
+	e.AddExDate(time.Date(1997, 9, 3, 11, 0, 0, 0, time.FixedZone("", 2*60*60)))
+	e.AddExdate("19970904T090000Z")
+	exdates, err := e.GetExDates()
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(1997, 9, 3, 9, 0, 0, 0, time.UTC), time.Date(1997, 9, 4, 9, 0, 0, 0, time.UTC)}, utcTimes(exdates))
+	occurrences, err := e.RRuleExpand(time.Date(1997, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1998, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC), time.Date(1997, 9, 5, 9, 0, 0, 0, time.UTC)}, utcTimes(occurrences))
+
+	allDay := NewEvent("test-exdate-all-day")
+	allDay.SetProperty(ComponentPropertyDtStart, "19970902", WithValue(string(ValueDataTypeDate)))
+	allDay.AddRrule("FREQ=DAILY;COUNT=3")
+	allDay.AddExDate(time.Date(1997, 9, 3, 0, 0, 0, 0, time.UTC))
+	assert.Contains(t, allDay.Serialize(), "EXDATE;VALUE=DATE:19970903\r\n")
+	exdates, err = allDay.GetExDates()
+	assert.NoError(t, err)
+	if assert.Len(t, exdates, 1) {
+		y, m, d := exdates[0].Date()
+		assert.Equal(t, []int{1997, 9, 3}, []int{y, int(m), d})
+	}
+	occurrences, err = allDay.RRuleExpand(time.Date(1997, 1, 1, 0, 0, 0, 0, time.Local), time.Date(1998, 1, 1, 0, 0, 0, 0, time.Local))
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 2)
+}
+
+func TestRDates(t *testing.T) {
+	e := NewEvent("test-rdate")
+	e.SetStartAt(time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC))
+	e.AddRrule("FREQ=DAILY;COUNT=2")
+	e.AddRDate(time.Date(1997, 9, 10, 11, 0, 0, 0, time.FixedZone("", 2*60*60)))
+	e.AddRDatePeriod(time.Date(1997, 9, 7, 9, 0, 0, 0, time.UTC), time.Date(1997, 9, 7, 10, 0, 0, 0, time.UTC))
+	e.AddRdate("19970903T090000Z")
+	assert.Contains(t, e.Serialize(), "RDATE:19970910T090000Z\r\n")
+	assert.Contains(t, e.Serialize(), "RDATE;VALUE=PERIOD:19970907T090000Z/19970907T100000Z\r\n")
+	rdates, err := e.GetRDates()
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(1997, 9, 10, 9, 0, 0, 0, time.UTC),
+		time.Date(1997, 9, 7, 9, 0, 0, 0, time.UTC),
+		time.Date(1997, 9, 3, 9, 0, 0, 0, time.UTC),
+	}, utcTimes(rdates))
+	occurrences, err := e.RRuleExpand(time.Date(1997, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1998, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(1997, 9, 3, 9, 0, 0, 0, time.UTC),
+		time.Date(1997, 9, 7, 9, 0, 0, 0, time.UTC),
+		time.Date(1997, 9, 10, 9, 0, 0, 0, time.UTC),
+	}, utcTimes(occurrences))
+}
+
+func TestMultipleRRules(t *testing.T) {
+	e := NewEvent("test-rrules")
+	e.SetStartAt(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	e.AddRrule("FREQ=DAILY;INTERVAL=2;COUNT=3")
+	e.AddRrule("FREQ=DAILY;INTERVAL=3;COUNT=3")
+	assert.Equal(t, "FREQ=DAILY;INTERVAL=2;COUNT=3", e.GetRRule())
+	assert.Equal(t, []string{"FREQ=DAILY;INTERVAL=2;COUNT=3", "FREQ=DAILY;INTERVAL=3;COUNT=3"}, e.GetRRules())
+	occurrences, err := e.RRuleExpand(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 7, 9, 0, 0, 0, time.UTC),
+	}, utcTimes(occurrences))
+
+	assert.Equal(t, "", NewEvent("single").GetRRule())
+}
+
+func TestRecurrenceID(t *testing.T) {
+	cal := NewCalendar()
+	master := cal.AddEvent("series")
+	master.SetStartAt(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	master.AddRrule("FREQ=DAILY;COUNT=5")
+
+	moved := cal.AddEvent("series")
+	moved.SetStartAt(time.Date(2024, 1, 2, 14, 0, 0, 0, time.UTC))
+	moved.SetRecurrenceID(time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), false)
+	assert.Contains(t, moved.Serialize(), "RECURRENCE-ID:20240102T090000Z\r\n")
+
+	later := cal.AddEvent("series")
+	later.SetStartAt(time.Date(2024, 1, 4, 10, 0, 0, 0, time.UTC))
+	later.SetRecurrenceID(time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC), true)
+	assert.Contains(t, later.Serialize(), "RECURRENCE-ID;RANGE=THISANDFUTURE:20240104T090000Z\r\n")
+
+	id, thisAndFuture, err := later.GetRecurrenceID()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC), id.UTC())
+	assert.True(t, thisAndFuture)
+	_, _, err = master.GetRecurrenceID()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+
+	occurrences, err := master.RRuleExpand(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 14, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC),
+	}, utcTimes(occurrences))
+}
+
+func TestRecurrenceIDMovedIntoRange(t *testing.T) {
+	cal := NewCalendar()
+	master := cal.AddEvent("series")
+	master.SetStartAt(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	master.AddRrule("FREQ=DAILY;COUNT=5")
+	earlier := cal.AddEvent("series")
+	earlier.SetStartAt(time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC))
+	earlier.SetRecurrenceID(time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC), false)
+
+	occurrences, err := master.RRuleExpand(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC),
+	}, utcTimes(occurrences))
+
+	earlier.SetRecurrenceID(time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC), true)
+	occurrences, err = master.RRuleExpand(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC),
+	}, utcTimes(occurrences))
+}
@@ -0,0 +1,561 @@
+package ics
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// timezoneHorizon is how far ahead recurring observances are expanded into transitions.
+	timezoneHorizon = time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// timezoneLocations caches the locations built from VTIMEZONE components by their serialized form, evicting the
+	// least recently used once it holds maxTimezoneLocations.
+	timezoneLocations = &locationCache{m: map[string]*list.Element{}, order: list.New()}
+)
+
+// maxTimezoneLocations is how many locations built from VTIMEZONE components are cached.
+const maxTimezoneLocations = 64
+
+type locationCache struct {
+	sync.Mutex
+	m     map[string]*list.Element
+	order *list.List
+}
+
+type locationCacheEntry struct {
+	key string
+	loc *time.Location
+}
+
+func (c *locationCache) get(key string) (*time.Location, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*locationCacheEntry).loc, true
+}
+
+func (c *locationCache) add(key string, loc *time.Location) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.m[key]; ok {
+		c.order.MoveToFront(e)
+		e.Value.(*locationCacheEntry).loc = loc
+		return
+	}
+	c.m[key] = c.order.PushFront(&locationCacheEntry{key: key, loc: loc})
+	for c.order.Len() > maxTimezoneLocations {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.m, oldest.Value.(*locationCacheEntry).key)
+	}
+}
+
+// loadLocation returns the location for a TZID. The VTIMEZONE definitions of the calendar take precedence over the
+// system timezone database, so timezones embedded in the calendar resolve without tzdata being installed.
+func (calendar *Calendar) loadLocation(tzid string) (*time.Location, error) {
+	if calendar != nil {
+		if tz, err := calendar.FindTimezone(tzid); err == nil {
+			if loc, err := tz.ToLocation(); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return time.LoadLocation(tzid)
+}
+
+// ToLocation builds a location from the STANDARD and DAYLIGHT observances of the timezone, so times in it can be
+// resolved without the system timezone database. Recurring observances are expanded up to the year 2100; the offset in
+// effect then is used for any later time. Recently used locations are cached by the content of the timezone.
+func (c *VTimezone) ToLocation() (*time.Location, error) {
+	key := c.Serialize()
+	if loc, ok := timezoneLocations.get(key); ok {
+		return loc, nil
+	}
+	data, err := c.tzData()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocationFromTZData(c.GetId(), data)
+	if err != nil {
+		return nil, err
+	}
+	timezoneLocations.add(key, loc)
+	return loc, nil
+}
+
+type tzZone struct {
+	offset int
+	isDST  bool
+	name   string
+}
+
+type tzTransition struct {
+	when int64
+	from int
+	zone tzZone
+}
+
+// transitions expands the onsets of every observance of the timezone, sorted by time.
+func (c *VTimezone) transitions() ([]tzTransition, error) {
+	var r []tzTransition
+	for _, o := range c.GetAllObservances() {
+		from, err := parseUtcOffset(o.GetTzOffsetFrom())
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s TZOFFSETFROM: %w", o.Type, err)
+		}
+		to, err := parseUtcOffset(o.GetTzOffsetTo())
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s TZOFFSETTO: %w", o.Type, err)
+		}
+		zone := tzZone{offset: to, isDST: o.Type == "Daylight", name: o.GetTzName()}
+		// Onsets are given in the local time in effect before the observance begins
+		loc := time.FixedZone("", from)
+		onsets, err := o.onsets(loc)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range onsets {
+			r = append(r, tzTransition{when: t.Unix(), from: from, zone: zone})
+		}
+	}
+	if len(r) == 0 {
+		return nil, errors.New("timezone has no observances")
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].when < r[j].when
+	})
+	return r, nil
+}
+
+func (o *VTimezoneObservance) onsets(loc *time.Location) ([]time.Time, error) {
+	p := o.GetProperty(ComponentPropertyDtStart)
+	if p == nil {
+		return nil, fmt.Errorf("%s is missing DTSTART", o.Type)
+	}
+	dtstart, err := time.ParseInLocation(icalTimestampFormatLocal, p.Value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s DTSTART: %w", o.Type, err)
+	}
+	var r []time.Time
+	rules := false
+	seen := map[int64]bool{}
+	for _, p := range o.Properties {
+		switch ComponentProperty(p.IANAToken) {
+		case ComponentPropertyRrule:
+			rule, err := ParseRecurrenceRule(p.Value)
+			if err != nil {
+				return nil, err
+			}
+			if err := rule.checkSupported(); err != nil {
+				return nil, err
+			}
+			rules = true
+			rule.iterate(dtstart, timezoneHorizon, func(t time.Time) bool {
+				if !seen[t.Unix()] {
+					seen[t.Unix()] = true
+					r = append(r, t)
+				}
+				return true
+			})
+		}
+	}
+	if !rules {
+		r = append(r, dtstart)
+	}
+	for _, p := range o.Properties {
+		switch ComponentProperty(p.IANAToken) {
+		case ComponentPropertyRdate:
+			for _, v := range strings.Split(p.Value, ",") {
+				t, err := time.ParseInLocation(icalTimestampFormatLocal, v, loc)
+				if err != nil {
+					return nil, fmt.Errorf("parsing %s RDATE: %w", o.Type, err)
+				}
+				r = append(r, t)
+			}
+		}
+	}
+	return r, nil
+}
+
+// tzData encodes the timezone in the TZif format read by time.LoadLocationFromTZData, see RFC 8536. A version 2 file
+// is written, with the 32-bit data first for readers which don't support 64-bit times.
+func (c *VTimezone) tzData() ([]byte, error) {
+	transitions, err := c.transitions()
+	if err != nil {
+		return nil, err
+	}
+	// The first zone is the one in effect before the first transition, named after an observance with its offset
+	first := tzZone{offset: transitions[0].from}
+	for _, t := range transitions {
+		if t.zone.offset == first.offset {
+			first = t.zone
+			break
+		}
+	}
+	zones := []tzZone{first}
+	index := map[tzZone]int{}
+	var indexes []byte
+	for _, t := range transitions {
+		i, ok := index[t.zone]
+		if !ok {
+			i = len(zones)
+			index[t.zone] = i
+			zones = append(zones, t.zone)
+		}
+		indexes = append(indexes, byte(i))
+	}
+	if len(zones) > math.MaxUint8 {
+		return nil, errors.New("timezone has too many distinct observances")
+	}
+	var names []byte
+	nameIndex := map[string]int{}
+	for _, z := range zones {
+		if _, ok := nameIndex[z.name]; !ok {
+			nameIndex[z.name] = len(names)
+			names = append(append(names, z.name...), 0)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	writeSection := func(is64 bool) {
+		var whens []int64
+		var idx []byte
+		for i, t := range transitions {
+			if !is64 && (t.when < math.MinInt32 || t.when > math.MaxInt32) {
+				continue
+			}
+			whens = append(whens, t.when)
+			idx = append(idx, indexes[i])
+		}
+		b.WriteString("TZif2")
+		b.Write(make([]byte, 15))
+		for _, n := range []int{0, 0, 0, len(whens), len(zones), len(names)} {
+			_ = binary.Write(b, binary.BigEndian, uint32(n))
+		}
+		for _, w := range whens {
+			if is64 {
+				_ = binary.Write(b, binary.BigEndian, w)
+			} else {
+				_ = binary.Write(b, binary.BigEndian, int32(w))
+			}
+		}
+		b.Write(idx)
+		for _, z := range zones {
+			_ = binary.Write(b, binary.BigEndian, int32(z.offset))
+			if z.isDST {
+				b.WriteByte(1)
+			} else {
+				b.WriteByte(0)
+			}
+			b.WriteByte(byte(nameIndex[z.name]))
+		}
+		b.Write(names)
+	}
+	writeSection(false)
+	writeSection(true)
+	// No POSIX TZ string, the last transition stays in effect after the horizon
+	b.WriteString("\n\n")
+	return b.Bytes(), nil
+}
+
+// NewTimezoneFromLocation builds a VTIMEZONE describing the location from the start of this year for the next 10 years,
+// such as one loaded by time.LoadLocation. Each kind of transition becomes a STANDARD or DAYLIGHT observance, with a
+// yearly RRULE where the transitions follow one and RDATEs otherwise. A location without transitions gets a single
+// STANDARD observance.
+func NewTimezoneFromLocation(loc *time.Location) (*VTimezone, error) {
+	return newTimezoneFromLocation(loc, time.Now(), 10)
+}
+
+// observanceKey identifies a kind of transition: the offsets before and after it and the name of the zone begun.
+type observanceKey struct {
+	from, to int
+	name     string
+}
+
+func newTimezoneFromLocation(loc *time.Location, now time.Time, years int) (*VTimezone, error) {
+	if loc == nil {
+		return nil, errors.New("location is nil")
+	}
+	start := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(years, 0, 0)
+	var keys []observanceKey
+	onsets := map[observanceKey][]time.Time{}
+	name, offset := start.In(loc).Zone()
+	for t := start; t.Before(end); t = t.Add(24 * time.Hour) {
+		next := t.Add(24 * time.Hour)
+		nextName, nextOffset := next.In(loc).Zone()
+		if nextName == name && nextOffset == offset {
+			continue
+		}
+		// Find the first second of the new zone
+		lo, hi := t.Unix(), next.Unix()
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if n, o := time.Unix(mid, 0).In(loc).Zone(); n == name && o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		k := observanceKey{from: offset, to: nextOffset, name: nextName}
+		if _, ok := onsets[k]; !ok {
+			keys = append(keys, k)
+		}
+		onsets[k] = append(onsets[k], time.Unix(hi, 0))
+		name, offset = nextName, nextOffset
+	}
+
+	tz := &VTimezone{}
+	tz.SetProperty(ComponentProperty(PropertyTzid), loc.String())
+	if len(keys) == 0 {
+		o := &Standard{}
+		o.SetProperty(ComponentPropertyDtStart, "19700101T000000")
+		o.SetProperty(ComponentProperty(PropertyTzoffsetfrom), formatUtcOffset(offset))
+		o.SetProperty(ComponentProperty(PropertyTzoffsetto), formatUtcOffset(offset))
+		o.SetProperty(ComponentProperty(PropertyTzname), name)
+		tz.Components = append(tz.Components, o)
+		return tz, nil
+	}
+	for _, k := range keys {
+		var o ComponentBase
+		// Onsets are written in the local time in effect before the transition
+		before := time.FixedZone("", k.from)
+		local := onsets[k]
+		for i := range local {
+			local[i] = local[i].In(before)
+		}
+		o.SetProperty(ComponentPropertyDtStart, local[0].Format(icalTimestampFormatLocal))
+		o.SetProperty(ComponentProperty(PropertyTzoffsetfrom), formatUtcOffset(k.from))
+		o.SetProperty(ComponentProperty(PropertyTzoffsetto), formatUtcOffset(k.to))
+		o.SetProperty(ComponentProperty(PropertyTzname), k.name)
+		if rrule, ok := yearlyRule(local); ok {
+			o.AddRrule(rrule)
+		} else {
+			for _, t := range local[1:] {
+				o.AddRdate(t.Format(icalTimestampFormatLocal))
+			}
+		}
+		if k.to > k.from {
+			tz.Components = append(tz.Components, &Daylight{o})
+		} else {
+			tz.Components = append(tz.Components, &Standard{o})
+		}
+	}
+	return tz, nil
+}
+
+// yearlyRule returns a yearly RRULE giving the onsets, one a year at the same local time on a weekday counted from the
+// start or end of the same month, if there is one.
+func yearlyRule(onsets []time.Time) (string, bool) {
+	if len(onsets) < 2 {
+		// A single transition, such as a permanent change of offset, doesn't recur
+		return "", false
+	}
+	first := onsets[0]
+	fromStart := (first.Day()-1)/7 + 1
+	fromEnd := -((daysIn(first.Year(), first.Month())-first.Day())/7 + 1)
+	for i, t := range onsets {
+		if t.Year() != first.Year()+i || t.Month() != first.Month() || t.Weekday() != first.Weekday() ||
+			t.Hour() != first.Hour() || t.Minute() != first.Minute() || t.Second() != first.Second() {
+			return "", false
+		}
+		if (t.Day()-1)/7+1 != fromStart {
+			fromStart = 0
+		}
+		if -((daysIn(t.Year(), t.Month())-t.Day())/7 + 1) != fromEnd {
+			fromEnd = 0
+		}
+	}
+	n := fromStart
+	if fromEnd == -1 {
+		// The last weekday of the month is preferred as it stays correct for five week months
+		n = fromEnd
+	}
+	if n == 0 {
+		return "", false
+	}
+	var day string
+	for code, wd := range weekdayCodes {
+		if wd == first.Weekday() {
+			day = code
+		}
+	}
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", first.Month(), n, day), true
+}
+
+// formatUtcOffset formats seconds east of UTC as a UTC-OFFSET value, with seconds only when there are some.
+func formatUtcOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		s += fmt.Sprintf("%02d", offset%60)
+	}
+	return s
+}
+
+// parseUtcOffset parses a UTC-OFFSET value into seconds east of UTC.
+func parseUtcOffset(s string) (int, error) {
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') || !isDigits(s[1:]) {
+		return 0, fmt.Errorf("malformed utc offset '%s'", s)
+	}
+	h, _ := strconv.Atoi(s[1:3])
+	m, _ := strconv.Atoi(s[3:5])
+	sec := 0
+	if len(s) == 7 {
+		sec, _ = strconv.Atoi(s[5:7])
+	}
+	r := h*3600 + m*60 + sec
+	if s[0] == '-' {
+		r = -r
+	}
+	return r, nil
+}
+
+// windowsTimezones maps the Windows timezone names Outlook and Exchange use as TZIDs to IANA names, following the
+// territory-neutral mappings of the CLDR windowsZones table.
+var windowsTimezones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"UTC-11":                          "Etc/GMT+11",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time":           "America/New_York",
+	"US Eastern Standard Time":        "America/Indiana/Indianapolis",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Venezuela Standard Time":         "America/Caracas",
+	"SA Western Standard Time":        "America/La_Paz",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"SA Eastern Standard Time":        "America/Cayenne",
+	"Azores Standard Time":            "Atlantic/Azores",
+	"Cape Verde Standard Time":        "Atlantic/Cape_Verde",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"Morocco Standard Time":           "Africa/Casablanca",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Middle East Standard Time":       "Asia/Beirut",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"FLE Standard Time":               "Europe/Kiev",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Arabic Standard Time":            "Asia/Baghdad",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Russian Standard Time":           "Europe/Moscow",
+	"E. Africa Standard Time":         "Africa/Nairobi",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Afghanistan Standard Time":       "Asia/Kabul",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"West Asia Standard Time":         "Asia/Tashkent",
+	"India Standard Time":             "Asia/Kolkata",
+	"Nepal Standard Time":             "Asia/Kathmandu",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"West Pacific Standard Time":      "Pacific/Port_Moresby",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"Fiji Standard Time":              "Pacific/Fiji",
+	"Tonga Standard Time":             "Pacific/Tongatapu",
+}
+
+// NormalizeTimezones replaces Windows timezone names used as TZIDs with their IANA names, both in the TZID parameters
+// of properties and in the VTIMEZONE components. When several VTIMEZONEs end up with the same TZID only the first is
+// kept. An error is returned if a renamed TZID can't be resolved, either from a VTIMEZONE of the calendar or from the
+// system timezone database.
+func (calendar *Calendar) NormalizeTimezones() error {
+	renamed := map[string]bool{}
+	var walk func(components []Component)
+	walk = func(components []Component) {
+		for _, c := range components {
+			c.WalkProperties(func(property Property, p *IANAProperty) bool {
+				if _, ok := c.(*VTimezone); ok && property == PropertyTzid {
+					if name, ok := windowsTimezones[p.Value]; ok {
+						p.Value = name
+						renamed[name] = true
+					}
+				}
+				tzids := p.ICalParameters[string(ParameterTzid)]
+				for i, tzid := range tzids {
+					if name, ok := windowsTimezones[tzid]; ok {
+						tzids[i] = name
+						renamed[name] = true
+					}
+				}
+				return true
+			})
+			walk(c.SubComponents())
+		}
+	}
+	walk(calendar.Components)
+
+	seen := map[string]bool{}
+	components := calendar.Components[:0]
+	for _, c := range calendar.Components {
+		if tz, ok := c.(*VTimezone); ok {
+			if seen[tz.GetId()] {
+				continue
+			}
+			seen[tz.GetId()] = true
+		}
+		components = append(components, c)
+	}
+	for i := len(components); i < len(calendar.Components); i++ {
+		calendar.Components[i] = nil
+	}
+	calendar.Components = components
+
+	names := make([]string, 0, len(renamed))
+	for name := range renamed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := calendar.loadLocation(name); err != nil {
+			return fmt.Errorf("%w: %s", ErrTimezoneNotFound, name)
+		}
+	}
+	return nil
+}
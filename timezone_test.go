@@ -1,8 +1,13 @@
 package ics
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTimeZone(t *testing.T) {
@@ -135,7 +140,10 @@ END:VCALENDAR
 		t.Log("-------------------")
 	}
 
-	timezone := calendar.FindTimezone("America/New_York")
+	timezone, err := calendar.FindTimezone("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
 	t.Log("timezone: \"America/New_York\" :", timezone)
 	t.Log("timezone.tzid:", timezone.GetId())
 	t.Log("timezone.tzurl:", timezone.GetUrl())
@@ -166,3 +174,370 @@ END:VCALENDAR
 	t.Log("-------------------")
 }
 
+func TestEmbeddedTimezone(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VTIMEZONE
+TZID:Custom Eastern
+BEGIN:STANDARD
+DTSTART:19671029T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+TZNAME:EST
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:19870405T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VTIMEZONE
+TZID:Taipei Standard Time
+BEGIN:STANDARD
+DTSTART:16010101T000000
+TZOFFSETFROM:+0800
+TZOFFSETTO:+0800
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:summer
+DTSTART;TZID=Custom Eastern:20210704T120000
+DTEND;TZID=Custom Eastern:20210704T130000
+END:VEVENT
+BEGIN:VEVENT
+UID:winter
+DTSTART;TZID=Custom Eastern:20210115T120000
+END:VEVENT
+BEGIN:VEVENT
+UID:taipei
+DTSTART;TZID=Taipei Standard Time:20211112T000000
+END:VEVENT
+END:VCALENDAR
+`
+	calendar, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]time.Time{
+		"summer": time.Date(2021, 7, 4, 16, 0, 0, 0, time.UTC),
+		"winter": time.Date(2021, 1, 15, 17, 0, 0, 0, time.UTC),
+		"taipei": time.Date(2021, 11, 11, 16, 0, 0, 0, time.UTC),
+	}
+	for _, event := range calendar.Events() {
+		start, err := event.GetStartAt()
+		if assert.NoError(t, err, event.Id()) {
+			assert.True(t, expected[event.Id()].Equal(start), "%s: %s", event.Id(), start)
+		}
+	}
+	end, err := calendar.Events()[0].GetEndAt()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 7, 4, 17, 0, 0, 0, time.UTC), end.UTC())
+	name, offset := end.Zone()
+	assert.Equal(t, "EDT", name)
+	assert.Equal(t, -4*60*60, offset)
+}
+
+func TestVTimezoneToLocation(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:Custom Sydney
+BEGIN:STANDARD
+DTSTART:20080406T030000
+RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=1SU
+TZOFFSETFROM:+1100
+TZOFFSETTO:+1000
+TZNAME:AEST
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:20081005T020000
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=1SU
+TZOFFSETFROM:+1000
+TZOFFSETTO:+1100
+TZNAME:AEDT
+END:DAYLIGHT
+END:VTIMEZONE
+END:VCALENDAR
+`
+	calendar, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := calendar.Timezones()[0].ToLocation()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Custom Sydney", loc.String())
+	for _, tc := range []struct {
+		when   time.Time
+		name   string
+		offset int
+	}{
+		{time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), "AEDT", 11 * 60 * 60},
+		{time.Date(2024, 7, 15, 0, 0, 0, 0, time.UTC), "AEST", 10 * 60 * 60},
+		{time.Date(2024, 4, 6, 15, 59, 59, 0, time.UTC), "AEDT", 11 * 60 * 60},
+		{time.Date(2024, 4, 6, 16, 0, 0, 0, time.UTC), "AEST", 10 * 60 * 60},
+	} {
+		name, offset := tc.when.In(loc).Zone()
+		assert.Equal(t, tc.name, name, tc.when.String())
+		assert.Equal(t, tc.offset, offset, tc.when.String())
+	}
+
+	_, err = (&VTimezone{}).ToLocation()
+	assert.Error(t, err)
+
+	tz, err := calendar.FindTimezone("Custom Sydney")
+	assert.NoError(t, err)
+	assert.Equal(t, "Custom Sydney", tz.GetId())
+	_, err = calendar.FindTimezone("Missing")
+	assert.True(t, errors.Is(err, ErrTimezoneNotFound))
+}
+
+func TestTimezoneLocationCacheBounded(t *testing.T) {
+	for i := 0; i < maxTimezoneLocations*2; i++ {
+		tz, err := NewTimezoneFromLocation(time.FixedZone(fmt.Sprintf("Zone %d", i), i*60))
+		if !assert.NoError(t, err) {
+			return
+		}
+		loc, err := tz.ToLocation()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, fmt.Sprintf("Zone %d", i), loc.String())
+	}
+	assert.Equal(t, maxTimezoneLocations, len(timezoneLocations.m))
+	assert.Equal(t, maxTimezoneLocations, timezoneLocations.order.Len())
+}
+
+func TestTimezoneObservanceMultipleRRules(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:US Eastern
+BEGIN:STANDARD
+DTSTART:19671029T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU;UNTIL=20061029T060000Z
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+TZNAME:EST
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:19870405T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=1SU;UNTIL=20060402T070000Z
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+END:VTIMEZONE
+END:VCALENDAR
+`
+	calendar, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := calendar.Timezones()[0].ToLocation()
+	if !assert.NoError(t, err) {
+		return
+	}
+	name, _ := time.Date(2021, 11, 5, 12, 0, 0, 0, time.UTC).In(loc).Zone()
+	assert.Equal(t, "EDT", name)
+	name, _ = time.Date(2021, 11, 8, 12, 0, 0, 0, time.UTC).In(loc).Zone()
+	assert.Equal(t, "EST", name)
+}
+
+func TestTzOffsetDuration(t *testing.T) {
+	o := &VTimezoneObservance{}
+	_, err := o.GetTzOffsetFromDuration()
+	assert.True(t, errors.Is(err, ErrPropertyNotFound))
+
+	o.SetProperty(ComponentProperty(PropertyTzoffsetfrom), "+0530")
+	o.SetProperty(ComponentProperty(PropertyTzoffsetto), "-034512")
+	from, err := o.GetTzOffsetFromDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Hour+30*time.Minute, from)
+	to, err := o.GetTzOffsetToDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, -(3*time.Hour + 45*time.Minute + 12*time.Second), to)
+
+	o.SetProperty(ComponentProperty(PropertyTzoffsetto), "0530")
+	_, err = o.GetTzOffsetToDuration()
+	assert.Error(t, err)
+}
+
+func TestNewTimezoneFromLocation(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		tzid      string
+		observed  int
+		contained []string
+	}{
+		{"America/New_York", 2, []string{
+			"BEGIN:DAYLIGHT\r\nDTSTART:20240310T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU\r\n",
+			"BEGIN:STANDARD\r\nDTSTART:20241103T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nTZNAME:EST\r\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\n",
+		}},
+		{"Europe/Berlin", 2, []string{"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\n", "RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU\r\n"}},
+		{"Asia/Tokyo", 1, []string{"TZOFFSETFROM:+0900\r\nTZOFFSETTO:+0900\r\nTZNAME:JST\r\n"}},
+	} {
+		loc, err := time.LoadLocation(tc.tzid)
+		if err != nil {
+			t.Skip(err)
+		}
+		tz, err := newTimezoneFromLocation(loc, now, 10)
+		if !assert.NoError(t, err, tc.tzid) {
+			continue
+		}
+		assert.Equal(t, tc.tzid, tz.GetId())
+		assert.Len(t, tz.GetAllObservances(), tc.observed, tc.tzid)
+		s := tz.Serialize()
+		for _, c := range tc.contained {
+			assert.Contains(t, s, c, tc.tzid)
+		}
+
+		built, err := tz.ToLocation()
+		if !assert.NoError(t, err, tc.tzid) {
+			continue
+		}
+		for when := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); when.Year() < 2034; when = when.Add(5 * time.Hour) {
+			name, offset := when.In(loc).Zone()
+			builtName, builtOffset := when.In(built).Zone()
+			if !assert.Equal(t, offset, builtOffset, "%s %s", tc.tzid, when) || !assert.Equal(t, name, builtName) {
+				break
+			}
+		}
+	}
+
+	assert.Equal(t, "+0530", formatUtcOffset(5*60*60+30*60))
+	assert.Equal(t, "-003015", formatUtcOffset(-(30*60 + 15)))
+	_, err := NewTimezoneFromLocation(nil)
+	assert.Error(t, err)
+}
+
+func TestTimezoneHistoricalObservances(t *testing.T) {
+	input := `BEGIN:VCALENDAR
+BEGIN:VTIMEZONE
+TZID:Eastern Standard Time
+BEGIN:STANDARD
+DTSTART:19671029T020000
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU;UNTIL=20061029T060000Z
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+TZNAME:EST
+END:STANDARD
+BEGIN:STANDARD
+DTSTART:20071104T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+TZNAME:EST
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:19740106T020000
+RDATE:19750223T020000
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+BEGIN:DAYLIGHT
+DTSTART:19760425T020000
+RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=-1SU;UNTIL=19860427T070000Z
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+BEGIN:DAYLIGHT
+DTSTART:19870405T020000
+RRULE:FREQ=YEARLY;BYMONTH=4;BYDAY=1SU;UNTIL=20060402T070000Z
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+BEGIN:DAYLIGHT
+DTSTART:20070311T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+TZNAME:EDT
+END:DAYLIGHT
+END:VTIMEZONE
+END:VCALENDAR
+`
+	calendar, err := ParseCalendar(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tz := calendar.Timezones()[0]
+	assert.Len(t, tz.GetStands(), 2)
+	assert.Len(t, tz.GetDaylights(), 4)
+	loc, err := tz.ToLocation()
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, tc := range []struct {
+		when time.Time
+		name string
+	}{
+		{time.Date(1974, 2, 1, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(1975, 1, 15, 12, 0, 0, 0, time.UTC), "EST"},
+		{time.Date(1975, 3, 1, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(1980, 4, 20, 12, 0, 0, 0, time.UTC), "EST"},
+		{time.Date(1980, 4, 28, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(1990, 4, 3, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(2005, 3, 20, 12, 0, 0, 0, time.UTC), "EST"},
+		{time.Date(2005, 10, 25, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(2005, 11, 3, 12, 0, 0, 0, time.UTC), "EST"},
+		{time.Date(2021, 3, 20, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC), "EDT"},
+		{time.Date(2021, 11, 10, 12, 0, 0, 0, time.UTC), "EST"},
+	} {
+		name, _ := tc.when.In(loc).Zone()
+		assert.Equal(t, tc.name, name, tc.when.String())
+	}
+}
+
+func TestNormalizeTimezones(t *testing.T) {
+	tz := func(tzid string) string {
+		return "BEGIN:VTIMEZONE\r\nTZID:" + tzid + "\r\n" +
+			"BEGIN:STANDARD\r\nDTSTART:19701101T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\n" +
+			"TZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\n" +
+			"BEGIN:DAYLIGHT\r\nDTSTART:19700308T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU\r\n" +
+			"TZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nEND:DAYLIGHT\r\nEND:VTIMEZONE\r\n"
+	}
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" +
+		tz("Eastern Standard Time") + tz("America/New_York") +
+		"BEGIN:VEVENT\r\nUID:outlook\r\nDTSTAMP:20240101T000000Z\r\n" +
+		"DTSTART;TZID=Eastern Standard Time:20240305T090000\r\nDTEND;TZID=Eastern Standard Time:20240305T100000\r\n" +
+		"RRULE:FREQ=DAILY;COUNT=3\r\nEXDATE;TZID=Eastern Standard Time:20240306T090000\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER;VALUE=DATE-TIME;TZID=Eastern Standard Time:20240305T083000\r\n" +
+		"END:VALARM\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:google\r\nDTSTAMP:20240101T000000Z\r\nDTSTART;TZID=America/New_York:20240305T090000\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	cal, err := ParseCalendar(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, cal.NormalizeTimezones())
+
+	timezones := cal.Timezones()
+	if assert.Len(t, timezones, 1) {
+		assert.Equal(t, "America/New_York", timezones[0].GetId())
+	}
+	s := cal.Serialize()
+	assert.NotContains(t, s, "Eastern Standard Time")
+	assert.Contains(t, s, "DTSTART;TZID=America/New_York:20240305T090000\r\n")
+	assert.Contains(t, s, "EXDATE;TZID=America/New_York:20240306T090000\r\n")
+
+	start, err := cal.Events()[0].GetStartAt()
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC), start.UTC())
+	}
+
+	distinct, err := ParseCalendar(strings.NewReader(strings.Replace(input, "America/New_York", "Custom/Zone", -1)))
+	if assert.NoError(t, err) {
+		assert.NoError(t, distinct.NormalizeTimezones())
+		assert.Len(t, distinct.Timezones(), 2)
+	}
+}
@@ -0,0 +1,376 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// requiredProperties are the properties RFC 5545 requires on each component.
+var requiredProperties = map[ComponentType][]Property{
+	ComponentVCalendar: {PropertyProductId, PropertyVersion},
+	ComponentVEvent:    {PropertyUid, PropertyDtstamp},
+	ComponentVTodo:     {PropertyUid, PropertyDtstamp},
+	ComponentVJournal:  {PropertyUid, PropertyDtstamp},
+	ComponentVFreeBusy: {PropertyUid, PropertyDtstamp},
+	ComponentVTimezone: {PropertyTzid},
+	ComponentStandard:  {PropertyDtstart, PropertyTzoffsetfrom, PropertyTzoffsetto},
+	ComponentDaylight:  {PropertyDtstart, PropertyTzoffsetfrom, PropertyTzoffsetto},
+	ComponentVAlarm:    {PropertyAction, PropertyTrigger},
+}
+
+// schedulingMethods are the METHODs RFC 5546 defines for each component type.
+var schedulingMethods = map[ComponentType][]Method{
+	ComponentVEvent: {MethodPublish, MethodRequest, MethodReply, MethodAdd, MethodCancel, MethodRefresh, MethodCounter,
+		MethodDeclinecounter},
+	ComponentVTodo: {MethodPublish, MethodRequest, MethodReply, MethodAdd, MethodCancel, MethodRefresh, MethodCounter,
+		MethodDeclinecounter},
+	ComponentVJournal:  {MethodPublish, MethodAdd, MethodCancel},
+	ComponentVFreeBusy: {MethodPublish, MethodRequest, MethodReply},
+}
+
+// componentTypes returns the types of the components, each once and in the order they first appear.
+func componentTypes(components []Component) []ComponentType {
+	var r []ComponentType
+	seen := map[ComponentType]bool{}
+	for _, c := range components {
+		if t := ComponentType(componentName(c)); !seen[t] {
+			seen[t] = true
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+// checkMethod returns an error if the calendar has a METHOD which doesn't apply to the given types of its components.
+// RFC 5546 limits a scheduling message to a single type of component, besides the timezones it uses.
+func (calendar *Calendar) checkMethod(types []ComponentType) error {
+	method, err := calendar.GetMethod()
+	if err != nil {
+		return nil
+	}
+	if strings.HasPrefix(string(method), "X-") {
+		return nil
+	}
+	var componentType ComponentType
+	for _, t := range types {
+		if _, ok := schedulingMethods[t]; !ok {
+			continue
+		}
+		if componentType != "" && componentType != t {
+			return fmt.Errorf("%s: METHOD %s can't mix %s and %s components", ComponentVCalendar, method, componentType, t)
+		}
+		componentType = t
+		found := false
+		for _, m := range schedulingMethods[t] {
+			found = found || m == method
+		}
+		if !found {
+			return fmt.Errorf("%s: METHOD %s doesn't apply to %s components", ComponentVCalendar, method, t)
+		}
+	}
+	if componentType == "" {
+		return fmt.Errorf("%s: METHOD %s without a component to schedule", ComponentVCalendar, method)
+	}
+	return nil
+}
+
+// checkStrict returns the first RFC 5545 violation found in the calendar: a missing required property, an unknown
+// property on a known component or a value which doesn't match its value type.
+func (calendar *Calendar) checkStrict() error {
+	if err := calendar.checkCalendarStrict(componentTypes(calendar.Components)); err != nil {
+		return err
+	}
+	return checkComponentsStrict(calendar.Components)
+}
+
+// checkCalendarStrict is checkStrict for the calendar properties alone, given the types of the components for checking
+// the METHOD against.
+func (calendar *Calendar) checkCalendarStrict(types []ComponentType) error {
+	props := make([]IANAProperty, 0, len(calendar.CalendarProperties))
+	for _, p := range calendar.CalendarProperties {
+		props = append(props, IANAProperty{p.BaseProperty})
+	}
+	if err := checkPropertiesStrict(ComponentVCalendar, props); err != nil {
+		return err
+	}
+	if err := calendar.checkMethod(types); err != nil {
+		return err
+	}
+	if cs := calendar.GetCalScale(); cs != CalScaleGregorian {
+		return fmt.Errorf("%s: unsupported CALSCALE %s", ComponentVCalendar, cs)
+	}
+	return nil
+}
+
+func checkComponentsStrict(components []Component) error {
+	for _, c := range components {
+		if _, ok := c.(*GeneralComponent); ok {
+			// Nothing is known about the properties of other components
+			continue
+		}
+		if err := checkPropertiesStrict(ComponentType(componentName(c)), c.UnknownPropertiesIANAProperties()); err != nil {
+			return err
+		}
+		if err := checkComponentsStrict(c.SubComponents()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPropertiesStrict(component ComponentType, props []IANAProperty) error {
+	for _, required := range requiredProperties[component] {
+		found := false
+		for _, p := range props {
+			found = found || strings.EqualFold(p.IANAToken, string(required))
+		}
+		if !found {
+			return fmt.Errorf("%s: missing required property %s", component, required)
+		}
+	}
+	for i := range props {
+		p := &props[i].BaseProperty
+		if _, known := defaultValueDataType(p.IANAToken); !known {
+			if isXName(p.IANAToken) {
+				continue
+			}
+			return fmt.Errorf("%s: unknown property %s", component, p.IANAToken)
+		}
+		if err := checkValue(p); err != nil {
+			return fmt.Errorf("%s: property %s: %w", component, p.IANAToken, err)
+		}
+	}
+	return nil
+}
+
+// checkValue returns an error if the value of the property can't be decoded from its ENCODING, doesn't match its value
+// type, or for a COLOR, if it isn't a CSS3 color.
+func checkValue(p *BaseProperty) error {
+	value, err := p.DecodedValue()
+	if err != nil {
+		return err
+	}
+	t := p.valueDataType()
+	switch t {
+	case ValueDataTypeText:
+		if strings.EqualFold(p.IANAToken, string(PropertyColor)) {
+			return checkColor(FromText(value))
+		}
+		return nil
+	case ValueDataTypeBinary, ValueDataTypeCalAddress, ValueDataTypeUri:
+		return nil
+	case ValueDataTypeDuration:
+		if _, err := parseDuration(value); err != nil {
+			return fmt.Errorf("invalid %s value: %w", t, err)
+		}
+		return nil
+	case ValueDataTypeBoolean, ValueDataTypeDate, ValueDataTypeDateTime, ValueDataTypeFloat, ValueDataTypeInteger,
+		ValueDataTypePeriod, ValueDataTypeRecur, ValueDataTypeTime, ValueDataTypeUtcOffset:
+		if _, err := icalToJCalValues(Property(strings.ToUpper(p.IANAToken)), t, value); err != nil {
+			return fmt.Errorf("invalid %s value: %w", t, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown value type %s", t)
+}
+
+// Severity is how serious a ValidationError is.
+type Severity string
+
+const (
+	// SeverityError marks a violation of RFC 5545 or RFC 5546.
+	SeverityError Severity = "ERROR"
+	// SeverityWarning marks something allowed but likely to be handled badly by other software.
+	SeverityWarning Severity = "WARNING"
+)
+
+// ValidationError is a problem found by Calendar.Validate. UID and Property are empty when they don't apply.
+type ValidationError struct {
+	Component string
+	UID       string
+	Property  string
+	Severity  Severity
+	Message   string
+}
+
+func (e ValidationError) Error() string {
+	s := e.Component
+	if e.UID != "" {
+		s += " " + e.UID
+	}
+	if e.Property != "" {
+		s += " " + e.Property
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Severity, s, e.Message)
+}
+
+// Validate checks the calendar against RFC 5545 and RFC 5546, returning every problem found in the order of the
+// components. Unlike strict parsing, unknown properties aren't reported.
+func (calendar *Calendar) Validate() []ValidationError {
+	props := make([]IANAProperty, 0, len(calendar.CalendarProperties))
+	for _, p := range calendar.CalendarProperties {
+		props = append(props, IANAProperty{p.BaseProperty})
+	}
+	r := validateProperties(ComponentVCalendar, "", props)
+	if err := calendar.checkMethod(componentTypes(calendar.Components)); err != nil {
+		r = append(r, ValidationError{
+			Component: string(ComponentVCalendar),
+			Property:  string(PropertyMethod),
+			Severity:  SeverityError,
+			Message:   err.Error(),
+		})
+	}
+	_, err := calendar.GetMethod()
+	return append(r, validateComponents(calendar.Components, err == nil)...)
+}
+
+func validateComponents(components []Component, hasMethod bool) []ValidationError {
+	var r []ValidationError
+	for _, c := range components {
+		if _, ok := c.(*GeneralComponent); ok {
+			continue
+		}
+		component := ComponentType(componentName(c))
+		uid := ""
+		if p := componentProperty(c, PropertyUid); p != nil {
+			uid = FromText(p.Value)
+		}
+		diagnose := func(property Property, severity Severity, message string) {
+			r = append(r, ValidationError{
+				Component: string(component),
+				UID:       uid,
+				Property:  string(property),
+				Severity:  severity,
+				Message:   message,
+			})
+		}
+		r = append(r, validateProperties(component, uid, c.UnknownPropertiesIANAProperties())...)
+		end := PropertyDtend
+		if component == ComponentVTodo {
+			end = PropertyDue
+		}
+		if componentProperty(c, end) != nil && componentProperty(c, PropertyDuration) != nil {
+			diagnose(PropertyDuration, SeverityError, fmt.Sprintf("can't be given along with %s", end))
+		}
+		attendees := false
+		for _, p := range c.UnknownPropertiesIANAProperties() {
+			switch Property(p.IANAToken) {
+			case PropertyRrule:
+				if rule, err := checkRecurrenceRule(p.Value); err != nil {
+					diagnose(PropertyRrule, SeverityError, err.Error())
+				} else if err := rule.checkSupported(); err != nil {
+					// Valid, but occurrences can't be listed by this package
+					diagnose(PropertyRrule, SeverityWarning, err.Error())
+				}
+			case PropertyAttendee:
+				attendees = true
+			}
+		}
+		// Alarms list the recipients of EMAIL alarms as attendees
+		if attendees && !hasMethod && component != ComponentVAlarm {
+			diagnose(PropertyAttendee, SeverityWarning, "attendees are only meaningful in a calendar with a METHOD")
+		}
+		r = append(r, validateComponents(c.SubComponents(), hasMethod)...)
+	}
+	return r
+}
+
+// validateProperties reports missing required properties and values which don't match their value type.
+func validateProperties(component ComponentType, uid string, props []IANAProperty) []ValidationError {
+	var r []ValidationError
+	for _, required := range requiredProperties[component] {
+		found := false
+		for _, p := range props {
+			found = found || strings.EqualFold(p.IANAToken, string(required))
+		}
+		if !found {
+			r = append(r, ValidationError{
+				Component: string(component),
+				UID:       uid,
+				Property:  string(required),
+				Severity:  SeverityError,
+				Message:   "missing required property",
+			})
+		}
+	}
+	for i := range props {
+		p := &props[i].BaseProperty
+		if _, known := defaultValueDataType(p.IANAToken); !known {
+			continue
+		}
+		if err := checkValue(p); err != nil {
+			r = append(r, ValidationError{
+				Component: string(component),
+				UID:       uid,
+				Property:  p.IANAToken,
+				Severity:  SeverityError,
+				Message:   err.Error(),
+			})
+		}
+	}
+	return r
+}
+
+// checkRecurrenceRule returns the parsed rule, or an error if the rule can't be parsed or combines rule parts RFC 5545
+// doesn't allow together.
+func checkRecurrenceRule(s string) (*RecurrenceRule, error) {
+	rule, err := ParseRecurrenceRule(s)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Count != 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL can't both be given")
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonth) == 0 && len(rule.ByMonthDay) == 0 &&
+		len(rule.ByYearDay) == 0 && len(rule.ByWeekNo) == 0 && len(rule.ByHour) == 0 && len(rule.ByMinute) == 0 &&
+		len(rule.BySecond) == 0 {
+		return nil, errors.New("BYSETPOS must be used along with another BYxxx rule part")
+	}
+	return rule, nil
+}
+
+// cssColorNames are the color keywords of CSS3, which RFC 7986 takes the values of COLOR from.
+var cssColorNames = map[string]bool{
+	"aliceblue": true, "antiquewhite": true, "aqua": true, "aquamarine": true, "azure": true, "beige": true,
+	"bisque": true, "black": true, "blanchedalmond": true, "blue": true, "blueviolet": true, "brown": true,
+	"burlywood": true, "cadetblue": true, "chartreuse": true, "chocolate": true, "coral": true,
+	"cornflowerblue": true, "cornsilk": true, "crimson": true, "cyan": true, "darkblue": true, "darkcyan": true,
+	"darkgoldenrod": true, "darkgray": true, "darkgreen": true, "darkgrey": true, "darkkhaki": true,
+	"darkmagenta": true, "darkolivegreen": true, "darkorange": true, "darkorchid": true, "darkred": true,
+	"darksalmon": true, "darkseagreen": true, "darkslateblue": true, "darkslategray": true, "darkslategrey": true,
+	"darkturquoise": true, "darkviolet": true, "deeppink": true, "deepskyblue": true, "dimgray": true,
+	"dimgrey": true, "dodgerblue": true, "firebrick": true, "floralwhite": true, "forestgreen": true,
+	"fuchsia": true, "gainsboro": true, "ghostwhite": true, "gold": true, "goldenrod": true, "gray": true,
+	"green": true, "greenyellow": true, "grey": true, "honeydew": true, "hotpink": true, "indianred": true,
+	"indigo": true, "ivory": true, "khaki": true, "lavender": true, "lavenderblush": true, "lawngreen": true,
+	"lemonchiffon": true, "lightblue": true, "lightcoral": true, "lightcyan": true, "lightgoldenrodyellow": true,
+	"lightgray": true, "lightgreen": true, "lightgrey": true, "lightpink": true, "lightsalmon": true,
+	"lightseagreen": true, "lightskyblue": true, "lightslategray": true, "lightslategrey": true,
+	"lightsteelblue": true, "lightyellow": true, "lime": true, "limegreen": true, "linen": true, "magenta": true,
+	"maroon": true, "mediumaquamarine": true, "mediumblue": true, "mediumorchid": true, "mediumpurple": true,
+	"mediumseagreen": true, "mediumslateblue": true, "mediumspringgreen": true, "mediumturquoise": true,
+	"mediumvioletred": true, "midnightblue": true, "mintcream": true, "mistyrose": true, "moccasin": true,
+	"navajowhite": true, "navy": true, "oldlace": true, "olive": true, "olivedrab": true, "orange": true,
+	"orangered": true, "orchid": true, "palegoldenrod": true, "palegreen": true, "paleturquoise": true,
+	"palevioletred": true, "papayawhip": true, "peachpuff": true, "peru": true, "pink": true, "plum": true,
+	"powderblue": true, "purple": true, "red": true, "rosybrown": true, "royalblue": true, "saddlebrown": true,
+	"salmon": true, "sandybrown": true, "seagreen": true, "seashell": true, "sienna": true, "silver": true,
+	"skyblue": true, "slateblue": true, "slategray": true, "slategrey": true, "snow": true, "springgreen": true,
+	"steelblue": true, "tan": true, "teal": true, "thistle": true, "tomato": true, "turquoise": true,
+	"violet": true, "wheat": true, "white": true, "whitesmoke": true, "yellow": true, "yellowgreen": true,
+}
+
+// checkColor returns an error if the value is neither a CSS3 color name nor a hex color such as #f00 or #ff0000.
+func checkColor(s string) error {
+	if cssColorNames[strings.ToLower(s)] {
+		return nil
+	}
+	if strings.HasPrefix(s, "#") && (len(s) == 4 || len(s) == 7) {
+		if _, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid color %q", s)
+}
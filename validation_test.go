@@ -0,0 +1,86 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	wrap := func(s string) string {
+		return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" + s + "END:VCALENDAR\r\n"
+	}
+	testCases := []struct {
+		name     string
+		input    string
+		expected []ValidationError
+	}{
+		{
+			name:  "valid event",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nDTSTART:20210101T090000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=DAILY;COUNT=2\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "missing uid and dtstamp",
+			input: wrap("BEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\n"),
+			expected: []ValidationError{
+				{Component: "VEVENT", Property: "UID", Severity: SeverityError, Message: "missing required property"},
+				{Component: "VEVENT", Property: "DTSTAMP", Severity: SeverityError, Message: "missing required property"},
+			},
+		},
+		{
+			name:  "dtend and duration",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nDTEND:20210101T100000Z\r\nDURATION:PT1H\r\nEND:VEVENT\r\n"),
+			expected: []ValidationError{
+				{Component: "VEVENT", UID: "1", Property: "DURATION", Severity: SeverityError, Message: "can't be given along with DTEND"},
+			},
+		},
+		{
+			name:  "count and until",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nRRULE:FREQ=DAILY;COUNT=2;UNTIL=20210201T000000Z\r\nEND:VEVENT\r\n"),
+			expected: []ValidationError{
+				{Component: "VEVENT", UID: "1", Property: "RRULE", Severity: SeverityError, Message: "COUNT and UNTIL can't both be given"},
+			},
+		},
+		{
+			name:  "unsupported rule part",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nRRULE:FREQ=YEARLY;BYYEARDAY=1,-1;BYSETPOS=1\r\nEND:VEVENT\r\n"),
+			expected: []ValidationError{
+				{Component: "VEVENT", UID: "1", Property: "RRULE", Severity: SeverityWarning, Message: "unsupported recurrence rule: part BYYEARDAY"},
+			},
+		},
+		{
+			name:  "misplaced rule part",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nRRULE:FREQ=MONTHLY;BYYEARDAY=1\r\nEND:VEVENT\r\n"),
+			expected: []ValidationError{
+				{Component: "VEVENT", UID: "1", Property: "RRULE", Severity: SeverityError, Message: "recurrence rule part BYYEARDAY isn't allowed with FREQ=MONTHLY"},
+			},
+		},
+		{
+			name:  "attendee without method",
+			input: wrap("BEGIN:VTODO\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nATTENDEE:mailto:a@example.com\r\nATTENDEE:mailto:b@example.com\r\nEND:VTODO\r\n"),
+			expected: []ValidationError{
+				{Component: "VTODO", UID: "1", Property: "ATTENDEE", Severity: SeverityWarning, Message: "attendees are only meaningful in a calendar with a METHOD"},
+			},
+		},
+		{
+			name:  "attendee with method",
+			input: wrap("METHOD:REQUEST\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nATTENDEE:mailto:a@example.com\r\nEND:VEVENT\r\n"),
+		},
+		{
+			name:  "invalid alarm",
+			input: wrap("BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20210101T000000Z\r\nBEGIN:VALARM\r\nACTION:EMAIL\r\nTRIGGER:soon\r\nATTENDEE:mailto:a@example.com\r\nEND:VALARM\r\nEND:VEVENT\r\n"),
+			expected: []ValidationError{
+				{Component: "VALARM", Property: "TRIGGER", Severity: SeverityError, Message: "invalid DURATION value: malformed duration 'soon'"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cal, err := ParseCalendar(strings.NewReader(tc.input))
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, cal.Validate())
+			}
+		})
+	}
+}
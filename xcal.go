@@ -0,0 +1,364 @@
+package ics
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// XCalNamespace is the XML namespace of xCal documents, see RFC 6321 §3.
+const XCalNamespace = "urn:ietf:params:xml:ns:icalendar-2.0"
+
+var (
+	// xCalParameterValueDataTypes are the value types of parameters which aren't text, see RFC 6321 §3.5.
+	xCalParameterValueDataTypes = map[Parameter]ValueDataType{
+		ParameterAltrep:        ValueDataTypeUri,
+		ParameterDelegatedFrom: ValueDataTypeCalAddress,
+		ParameterDelegatedTo:   ValueDataTypeCalAddress,
+		ParameterDir:           ValueDataTypeUri,
+		ParameterMember:        ValueDataTypeCalAddress,
+		ParameterRsvp:          ValueDataTypeBoolean,
+		ParameterSentBy:        ValueDataTypeCalAddress,
+	}
+	// xCalStructuredFields are the element names of the fields of structured property values.
+	xCalStructuredFields = map[Property][]string{
+		PropertyGeo:           {"latitude", "longitude"},
+		PropertyRequestStatus: {"code", "description", "data"},
+	}
+)
+
+// xmlElement is a generic XML element, xCal is converted to and from a tree of these.
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Children []xmlElement `xml:",any"`
+	Text     string       `xml:",chardata"`
+}
+
+func (e *xmlElement) child(name string) *xmlElement {
+	for i := range e.Children {
+		if e.Children[i].XMLName.Local == name {
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+func newXMLElement(name string, children ...xmlElement) xmlElement {
+	return xmlElement{XMLName: xml.Name{Local: name}, Children: children}
+}
+
+func newXMLTextElement(name string, text string) xmlElement {
+	return xmlElement{XMLName: xml.Name{Local: name}, Text: text}
+}
+
+// MarshalXML encodes the calendar as xCal, the XML representation of iCalendar defined in RFC 6321. The calendar is
+// always written as an icalendar element in the XCalNamespace namespace.
+func (calendar *Calendar) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	props := make([]xmlElement, 0, len(calendar.CalendarProperties))
+	for i := range calendar.CalendarProperties {
+		props = append(props, propertyToXCal(&calendar.CalendarProperties[i].BaseProperty))
+	}
+	components, err := componentsToXCal(calendar.Components)
+	if err != nil {
+		return err
+	}
+	vcalendar := newXMLElement("vcalendar", newXMLElement("properties", props...), newXMLElement("components", components...))
+	root := newXMLElement("icalendar", vcalendar)
+	// Set as an attribute, a namespaced root would make the encoder reset the namespace of its children
+	root.Attrs = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: XCalNamespace}}
+	return enc.Encode(root)
+}
+
+// UnmarshalXML decodes an xCal icalendar or vcalendar element, replacing the properties and components of the calendar.
+func (calendar *Calendar) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	root := xmlElement{}
+	if err := d.DecodeElement(&root, &start); err != nil {
+		return err
+	}
+	vcalendar := &root
+	if root.XMLName.Local == "icalendar" {
+		vcalendar = root.child("vcalendar")
+		if vcalendar == nil {
+			return errors.New("malformed xcal; expected a vcalendar element")
+		}
+	}
+	name, cb, err := xCalToComponentBase(vcalendar)
+	if err != nil {
+		return err
+	}
+	if name != string(ComponentVCalendar) {
+		return fmt.Errorf("malformed xcal; expected a vcalendar, got %s", name)
+	}
+	calendar.CalendarProperties = make([]CalendarProperty, 0, len(cb.Properties))
+	for _, p := range cb.Properties {
+		calendar.CalendarProperties = append(calendar.CalendarProperties, CalendarProperty{p.BaseProperty})
+	}
+	calendar.Components = cb.Components
+	if calendar.Components == nil {
+		calendar.Components = []Component{}
+	}
+	for _, c := range calendar.Components {
+		c.setCalendar(calendar)
+	}
+	return nil
+}
+
+func componentsToXCal(components []Component) ([]xmlElement, error) {
+	r := make([]xmlElement, 0, len(components))
+	for _, c := range components {
+		name := componentName(c)
+		if name == "" {
+			return nil, fmt.Errorf("unsupported component type %T", c)
+		}
+		properties := c.UnknownPropertiesIANAProperties()
+		props := make([]xmlElement, 0, len(properties))
+		for i := range properties {
+			props = append(props, propertyToXCal(&properties[i].BaseProperty))
+		}
+		sub, err := componentsToXCal(c.SubComponents())
+		if err != nil {
+			return nil, err
+		}
+		e := newXMLElement(strings.ToLower(name), newXMLElement("properties", props...))
+		if len(sub) > 0 {
+			e.Children = append(e.Children, newXMLElement("components", sub...))
+		}
+		r = append(r, e)
+	}
+	return r, nil
+}
+
+func propertyToXCal(p *BaseProperty) xmlElement {
+	e := newXMLElement(strings.ToLower(p.IANAToken))
+	params := newXMLElement("parameters")
+	for _, k := range sortedParameterKeys(p.ICalParameters) {
+		t := ValueDataTypeText
+		if pt, ok := xCalParameterValueDataTypes[Parameter(strings.ToUpper(k))]; ok {
+			t = pt
+		}
+		param := newXMLElement(strings.ToLower(k))
+		for _, v := range p.ICalParameters[k] {
+			if t == ValueDataTypeBoolean {
+				v = strings.ToLower(v)
+			}
+			param.Children = append(param.Children, newXMLTextElement(strings.ToLower(string(t)), v))
+		}
+		params.Children = append(params.Children, param)
+	}
+	if len(params.Children) > 0 {
+		e.Children = append(e.Children, params)
+	}
+	t := p.representedValueDataType()
+	values, err := icalToXCalValues(Property(strings.ToUpper(p.IANAToken)), t, p.Value)
+	if err != nil {
+		// Values which don't match their type are passed on verbatim
+		values = []xmlElement{newXMLTextElement(jCalTypeUnknown, p.Value)}
+	}
+	e.Children = append(e.Children, values...)
+	return e
+}
+
+func icalToXCalValues(property Property, t ValueDataType, value string) ([]xmlElement, error) {
+	name := strings.ToLower(string(t))
+	if t == jCalTypeUnknown {
+		return []xmlElement{newXMLTextElement(name, value)}, nil
+	}
+	if fieldNames, ok := xCalStructuredFields[property]; ok {
+		var fields []xmlElement
+		for i, f := range splitUnescaped(value, ';') {
+			if i >= len(fieldNames) {
+				return nil, fmt.Errorf("too many fields in %s", property)
+			}
+			if property == PropertyGeo {
+				if _, err := strconv.ParseFloat(f, 64); err != nil {
+					return nil, err
+				}
+			} else {
+				f = FromText(f)
+			}
+			fields = append(fields, newXMLTextElement(fieldNames[i], f))
+		}
+		return fields, nil
+	}
+	raw := []string{value}
+	if listProperties[property] {
+		raw = splitUnescaped(value, ',')
+	}
+	r := make([]xmlElement, 0, len(raw))
+	for _, s := range raw {
+		v, err := icalToXCalValue(t, s)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
+
+func icalToXCalValue(t ValueDataType, s string) (xmlElement, error) {
+	name := strings.ToLower(string(t))
+	switch t {
+	case ValueDataTypeText:
+		return newXMLTextElement(name, FromText(s)), nil
+	case ValueDataTypeDate, ValueDataTypeDateTime, ValueDataTypeTime:
+		v, err := icalToISOTime(t, s)
+		return newXMLTextElement(name, v), err
+	case ValueDataTypeUtcOffset:
+		v, err := icalToISOUtcOffset(s)
+		return newXMLTextElement(name, v), err
+	case ValueDataTypePeriod:
+		v, err := icalToISOPeriod(s)
+		if err != nil {
+			return xmlElement{}, err
+		}
+		parts := strings.SplitN(v, "/", 2)
+		end := newXMLTextElement("end", parts[1])
+		if strings.Contains(parts[1], "P") {
+			end.XMLName.Local = "duration"
+		}
+		return newXMLElement(name, newXMLTextElement("start", parts[0]), end), nil
+	case ValueDataTypeInteger:
+		_, err := strconv.Atoi(s)
+		return newXMLTextElement(name, s), err
+	case ValueDataTypeFloat:
+		_, err := strconv.ParseFloat(s, 64)
+		return newXMLTextElement(name, s), err
+	case ValueDataTypeBoolean:
+		b, err := strconv.ParseBool(s)
+		return newXMLTextElement(name, strconv.FormatBool(b)), err
+	case ValueDataTypeRecur:
+		parts, err := splitRecur(s)
+		if err != nil {
+			return xmlElement{}, err
+		}
+		recur := newXMLElement(name)
+		for _, part := range parts {
+			for _, v := range strings.Split(part.Value.(string), ",") {
+				if part.Key == "UNTIL" {
+					t := ValueDataTypeDateTime
+					if len(v) == 8 {
+						t = ValueDataTypeDate
+					}
+					if v, err = icalToISOTime(t, v); err != nil {
+						return xmlElement{}, err
+					}
+				}
+				recur.Children = append(recur.Children, newXMLTextElement(strings.ToLower(part.Key), v))
+			}
+		}
+		return recur, nil
+	}
+	return newXMLTextElement(name, s), nil
+}
+
+func xCalToComponentBase(e *xmlElement) (string, ComponentBase, error) {
+	cb := ComponentBase{}
+	name := strings.ToUpper(e.XMLName.Local)
+	if props := e.child("properties"); props != nil {
+		for i := range props.Children {
+			p, err := xCalToProperty(&props.Children[i])
+			if err != nil {
+				return "", cb, err
+			}
+			cb.Properties = append(cb.Properties, IANAProperty{*p})
+		}
+	}
+	if components := e.child("components"); components != nil {
+		for i := range components.Children {
+			subName, sub, err := xCalToComponentBase(&components.Children[i])
+			if err != nil {
+				return "", cb, err
+			}
+			cb.Components = append(cb.Components, newComponent(subName, sub))
+		}
+	}
+	return name, cb, nil
+}
+
+func xCalToProperty(e *xmlElement) (*BaseProperty, error) {
+	r := &BaseProperty{
+		IANAToken:      strings.ToUpper(e.XMLName.Local),
+		ICalParameters: map[string][]string{},
+	}
+	var values []string
+	var t ValueDataType
+	var fields []string
+	for _, c := range e.Children {
+		switch {
+		case c.XMLName.Local == "parameters":
+			for _, param := range c.Children {
+				k := strings.ToUpper(param.XMLName.Local)
+				for _, v := range param.Children {
+					if Parameter(k) == ParameterRsvp {
+						v.Text = strings.ToUpper(v.Text)
+					}
+					r.ICalParameters[k] = append(r.ICalParameters[k], v.Text)
+				}
+			}
+		case len(xCalStructuredFields[Property(r.IANAToken)]) > 0:
+			f := c.Text
+			if Property(r.IANAToken) != PropertyGeo {
+				f = ToText(f)
+			}
+			fields = append(fields, f)
+		default:
+			ct := ValueDataType(strings.ToUpper(c.XMLName.Local))
+			if t != "" && t != ct {
+				return nil, fmt.Errorf("malformed xcal; mixed value types in %s", r.IANAToken)
+			}
+			t = ct
+			values = append(values, xCalToICalValue(t, &c))
+		}
+	}
+	if fields != nil {
+		values = append(values, strings.Join(fields, ";"))
+		t, _ = defaultValueDataType(r.IANAToken)
+	}
+	if t == "" {
+		return nil, fmt.Errorf("malformed xcal; no value for %s", r.IANAToken)
+	}
+	r.Value = strings.Join(values, ",")
+	r.setRepresentedValueDataType(t)
+	return r, nil
+}
+
+func xCalToICalValue(t ValueDataType, e *xmlElement) string {
+	switch t {
+	case ValueDataTypeText:
+		return ToText(e.Text)
+	case ValueDataTypeDate, ValueDataTypeDateTime, ValueDataTypeTime:
+		return isoToICalTime(strings.TrimSpace(e.Text))
+	case ValueDataTypeUtcOffset:
+		return strings.Replace(strings.TrimSpace(e.Text), ":", "", -1)
+	case ValueDataTypeBoolean:
+		return strings.ToUpper(strings.TrimSpace(e.Text))
+	case ValueDataTypePeriod:
+		var parts []string
+		for _, c := range e.Children {
+			if c.XMLName.Local == "duration" {
+				parts = append(parts, strings.TrimSpace(c.Text))
+			} else {
+				parts = append(parts, isoToICalTime(strings.TrimSpace(c.Text)))
+			}
+		}
+		return strings.Join(parts, "/")
+	case ValueDataTypeRecur:
+		parts := map[string]string{}
+		for _, c := range e.Children {
+			k := strings.ToUpper(c.XMLName.Local)
+			v := strings.TrimSpace(c.Text)
+			if k == "UNTIL" {
+				v = isoToICalTime(v)
+			}
+			if parts[k] != "" {
+				v = parts[k] + "," + v
+			}
+			parts[k] = v
+		}
+		return joinRecur(parts)
+	}
+	return e.Text
+}
@@ -0,0 +1,70 @@
+package ics
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXCalRoundTrip(t *testing.T) {
+	err := filepath.Walk("./testdata/rfc5545sec4/", func(path string, info os.FileInfo, _ error) error {
+		if info.IsDir() {
+			return nil
+		}
+		inputBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cal, err := ParseCalendar(strings.NewReader(string(inputBytes)))
+		if !assert.NoError(t, err, path) {
+			return nil
+		}
+		b, err := xml.Marshal(cal)
+		if !assert.NoError(t, err, path) {
+			return nil
+		}
+		parsed := &Calendar{}
+		if assert.NoError(t, xml.Unmarshal(b, parsed), path) {
+			// Compare through jCal as parameter order isn't stable in the iCalendar output
+			expected, _ := json.Marshal(cal)
+			actual, _ := json.Marshal(parsed)
+			assert.Equal(t, string(expected), string(actual), path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot read test directory: %v", err)
+	}
+}
+
+func TestXCalMarshal(t *testing.T) {
+	cal := NewCalendarFor("test")
+	event := cal.AddEvent("123")
+	event.SetStartAt(time.Date(1997, 9, 2, 9, 0, 0, 0, time.UTC))
+	event.SetSummary("Planning, meeting", WithCN("Bob"))
+	event.AddRrule("FREQ=WEEKLY;COUNT=2;BYDAY=TU,TH")
+	event.SetGeo(37.386013, -122.082932)
+
+	b, err := xml.Marshal(cal)
+	assert.NoError(t, err)
+	expected := `<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0"><vcalendar><properties>` +
+		`<version><text>2.0</text></version><prodid><text>-//test//Golang ICS Library</text></prodid></properties>` +
+		`<components><vevent><properties><uid><text>123</text></uid><dtstart><date-time>1997-09-02T09:00:00Z</date-time></dtstart>` +
+		`<summary><parameters><cn><text>Bob</text></cn></parameters><text>Planning, meeting</text></summary>` +
+		`<rrule><recur><freq>WEEKLY</freq><count>2</count><byday>TU</byday><byday>TH</byday></recur></rrule>` +
+		`<geo><latitude>37.386013</latitude><longitude>-122.082932</longitude></geo>` +
+		`</properties></vevent></components></vcalendar></icalendar>`
+	assert.Equal(t, expected, string(b))
+
+	parsed := &Calendar{}
+	if assert.NoError(t, xml.Unmarshal(b, parsed)) {
+		assert.Equal(t, cal.Serialize(), parsed.Serialize())
+	}
+}